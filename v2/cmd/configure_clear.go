@@ -9,7 +9,6 @@ import (
 
 	"github.com/manifoldco/promptui"
 	"github.com/pydio/cells-client/v2/rest"
-	cells_sdk "github.com/pydio/cells-sdk-go"
 	"github.com/spf13/cobra"
 )
 
@@ -20,9 +19,9 @@ var clearCmd = &cobra.Command{
 	Short: "Clear current configuration",
 	Long:  "Clear current authentication data from your local keyring",
 	Run: func(cmd *cobra.Command, args []string) {
-		filePath := rest.DefaultConfigFilePath()
+		filePath := rest.GetConfigFilePath()
 		if s, err := ioutil.ReadFile(filePath); err == nil {
-			var c cells_sdk.SdkConfig
+			var c rest.CecConfig
 			if err = json.Unmarshal(s, &c); err == nil {
 				if !noKeyringDefined {
 					// First clean the keyring
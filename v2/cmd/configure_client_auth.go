@@ -55,7 +55,7 @@ You can also go through the whole process in a non-interactive manner by using t
 				fmt.Println(promptui.IconWarn + " Cannot save token in keyring! " + err.Error())
 			}
 		}
-		filePath := rest.DefaultConfigFilePath()
+		filePath := rest.GetConfigFilePath()
 		data, _ := json.Marshal(newConf)
 		err = ioutil.WriteFile(filePath, data, 0600)
 		if err != nil {
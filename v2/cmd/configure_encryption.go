@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+
+	"github.com/pydio/cells-client/v2/rest"
+)
+
+var (
+	encryptionImport   string
+	encryptionGenerate string
+	encryptionClear    bool
+)
+
+var configureEncryptionCmd = &cobra.Command{
+	Use:   "encryption",
+	Short: "Store the key used to encrypt and decrypt files in the keyring",
+	Long: `
+Store a key file in the local machine keyring, so that 'scp --encrypt' and 'put --encrypt' use it to encrypt files
+on the client side, and downloads use it to decrypt them, without passing --key-file each time.
+
+Use --generate to create a new random key: it is written to the passed file, that you must keep in a safe place,
+as encrypted files cannot be recovered without it. Use --import to store an existing key file, e.g. on another machine.
+`,
+	Example: `
+# Create a new key and keep a backup copy
+` + os.Args[0] + ` configure encryption --generate ~/cells.key
+
+# Use the same key on another machine
+` + os.Args[0] + ` configure encryption --import ./cells.key
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if encryptionClear {
+			if e := rest.ClearEncryptionKey(); e != nil {
+				log.Fatal(e)
+			}
+			fmt.Println(promptui.IconGood + " Removed encryption key from keyring")
+			return
+		}
+
+		var data []byte
+		var e error
+		switch {
+		case encryptionGenerate != "" && encryptionImport != "":
+			log.Fatal("use either --generate or --import")
+		case encryptionGenerate != "":
+			if _, e := os.Stat(encryptionGenerate); e == nil {
+				log.Fatalf("%s already exists, it will not be overwritten", encryptionGenerate)
+			}
+			if data, e = rest.GenerateEncryptionKey(); e != nil {
+				log.Fatal(e)
+			}
+			if e = ioutil.WriteFile(encryptionGenerate, data, 0600); e != nil {
+				log.Fatal(e)
+			}
+		case encryptionImport != "":
+			if data, e = ioutil.ReadFile(encryptionImport); e != nil {
+				log.Fatal(e)
+			}
+		default:
+			cmd.Help()
+			return
+		}
+		if e = rest.StoreEncryptionKey(data); e != nil {
+			log.Fatalf("could not store the encryption key in the keyring: %s", e.Error())
+		}
+		fmt.Println(promptui.IconGood + " Encryption key stored in keyring")
+	},
+}
+
+func init() {
+	flags := configureEncryptionCmd.PersistentFlags()
+	flags.StringVar(&encryptionGenerate, "generate", "", "Generate a new random key, write it to this file and store it")
+	flags.StringVar(&encryptionImport, "import", "", "Store the key read from this file")
+	flags.BoolVar(&encryptionClear, "clear", false, "Remove the encryption key from the keyring")
+	configureCmd.AddCommand(configureEncryptionCmd)
+}
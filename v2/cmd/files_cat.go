@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pydio/cells-client/v2/rest"
+)
+
+var catCmd = &cobra.Command{
+	Use:   "cat",
+	Short: "Write the content of a remote file to the standard output",
+	Long: `
+Stream the content of a file of your Cells server to the standard output, so that it can be piped to another command
+without being stored on the local disk first. Files that have been encrypted on upload are decrypted
+with the key passed with --passphrase-file or --key-file, or the one stored with 'configure encryption'.
+`,
+	Example: `
+# Display a remote file
+` + os.Args[0] + ` cat common-files/notes.txt
+
+# Restore a database dump
+` + os.Args[0] + ` cat common-files/backups/db.sql.gz | gunzip | psql mydb
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		if e := setEncryptionKey(); e != nil {
+			log.Fatal(e)
+		}
+		p := strings.Trim(args[0], "/")
+		reader, _, e := rest.OpenFile(ctx, p)
+		if e != nil {
+			log.Fatalf("could not read %s: %s", p, e.Error())
+		}
+		defer reader.Close()
+		if _, e := io.Copy(os.Stdout, reader); e != nil {
+			log.Fatal(e)
+		}
+	},
+}
+
+func init() {
+	addEncryptionFlags(catCmd)
+	RootCmd.AddCommand(catCmd)
+}
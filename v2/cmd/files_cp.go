@@ -31,12 +31,13 @@ To copy files from your local machine to your server (and vice versa), rather se
 	Example: cpCmdExample,
 	Args:    cobra.MinimumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		source := args[0]
 		target := args[1]
 
 		var sourceNodes []string
 		if path.Base(source) == "*" {
-			nodes, err := rest.ListNodesPath(source)
+			nodes, err := rest.ListNodesPath(ctx, source)
 			if err != nil {
 				log.Println("could not list nodes path", err)
 			}
@@ -46,12 +47,12 @@ To copy files from your local machine to your server (and vice versa), rather se
 		}
 
 		params := rest.CopyParams(sourceNodes, target)
-		jobID, err := rest.CopyJob(params)
+		jobID, err := rest.CopyJob(ctx, params)
 		if err != nil {
 			log.Fatalln("could not run job:", err.Error())
 		}
 
-		err = rest.MonitorJob(jobID)
+		err = rest.MonitorJob(ctx, jobID)
 		if err != nil {
 			log.Fatalln("could not monitor job", err.Error())
 		}
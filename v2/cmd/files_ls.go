@@ -104,6 +104,7 @@ Note that you can only use *one* of the three above flags at a time.
 `,
 	Example: lsCmdExample,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
 		// Retrieve requested display type and check it is valid
 		dt := sanityCheck()
@@ -116,14 +117,14 @@ Note that you can only use *one* of the three above flags at a time.
 		p := strings.Trim(lsPath, "/")
 
 		// Connect to the Cells API
-		ctx, apiClient, err := rest.GetApiClient()
+		_, apiClient, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		var exists bool
 		if p != "" {
-			_, exists = rest.StatNode(p)
+			_, exists = rest.StatNode(ctx, p)
 		}
 
 		if lsExists {
@@ -35,6 +35,7 @@ even if 'parents' flags is set, trying to create a folder in an unknown/unexisti
 ` + os.Args[0] + ` mkdir -p common-files/a/folder/that/does/not/exits
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
 		if len(args) < 1 {
 			log.Fatal(fmt.Errorf("please provide the target path"))
@@ -46,7 +47,7 @@ even if 'parents' flags is set, trying to create a folder in an unknown/unexisti
 		}
 
 		// Connect to the Pydio API via the sdkConfig
-		ctx, apiClient, err := rest.GetApiClient()
+		_, apiClient, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -91,7 +92,7 @@ even if 'parents' flags is set, trying to create a folder in an unknown/unexisti
 			log.Fatal("error while calling CreateNodes:", err)
 		}
 		// Wait that it is indexed
-		e := rest.RetryCallback(func() error {
+		e := rest.RetryCallback(ctx, func() error {
 			_, e := apiClient.TreeService.HeadNode(&tree_service.HeadNodeParams{Node: dir, Context: ctx})
 			if e != nil {
 				fmt.Println("Waiting for folder to be correctly indexed...")
@@ -28,19 +28,20 @@ var filesMvCmd = &cobra.Command{
 	Example: filesMvCmdExample,
 	Args:    cobra.MinimumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
 		source := args[0]
 		target := args[1]
 
 		var sourceNodes []string
 		if path.Base(source) == "*" {
-			nodes, err := rest.ListNodesPath(source)
+			nodes, err := rest.ListNodesPath(ctx, source)
 			if err != nil {
 				log.Println("could not list the nodes path", err)
 			}
 			sourceNodes = nodes
 		} else {
-			_, exists := rest.StatNode(source)
+			_, exists := rest.StatNode(ctx, source)
 			if !exists {
 				log.Fatalf("This node does not exist: [%v]\n", source)
 			}
@@ -48,12 +49,12 @@ var filesMvCmd = &cobra.Command{
 		}
 
 		params := rest.MoveParams(sourceNodes, target)
-		jobID, err := rest.MoveJob(params)
+		jobID, err := rest.MoveJob(ctx, params)
 		if err != nil {
 			log.Fatalln("Could not run job:", err.Error())
 		}
 
-		err = rest.MonitorJob(jobID)
+		err = rest.MonitorJob(ctx, jobID)
 		if err != nil {
 			log.Fatalln("Could not monitor job:", err.Error())
 		}
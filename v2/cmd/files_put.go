@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pydio/cells-client/v2/rest"
+)
+
+var putContentType string
+
+var putCmd = &cobra.Command{
+	Use:   "put",
+	Short: "Upload the standard input to a remote file",
+	Long: `
+Upload the content of the standard input to a file of your Cells server, so that the output of another command
+can be stored on the server without being written on the local disk first. Pass '-' as second argument to read
+from the standard input.
+
+As the length of the content is not known in advance, it is sent by parts of 50MB (see --part-size) as soon as 
+they are read. Because S3 limits uploads to 10,000 parts, the default part size allows streams of up to about 500GB:
+increase the part size for bigger streams.
+
+The MIME type of the file is detected from the extension of the target path or from the first bytes of the content,
+use --content-type to set it explicitly.
+
+With --encrypt, the content is encrypted on the fly with the key passed with --passphrase-file or --key-file,
+or the one stored with 'configure encryption' (see 'scp --help' for details).
+`,
+	Example: `
+# Backup a database
+pg_dump mydb | gzip | ` + os.Args[0] + ` put common-files/backups/db.sql.gz -
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		if args[1] != "-" {
+			log.Fatalf("only '-' (standard input) is supported as source, use '%s scp' to upload local files", os.Args[0])
+		}
+		if e := setTransferOptions(); e != nil {
+			log.Fatal(e)
+		}
+		if e := setEncryptionKey(); e != nil {
+			log.Fatal(e)
+		}
+		if putContentType != "" {
+			var e error
+			if rest.ContentType, e = rest.ParseContentType(putContentType); e != nil {
+				log.Fatal(e)
+			}
+		}
+		p := strings.Trim(args[0], "/")
+		if e := rest.PutStream(ctx, p, os.Stdin); e != nil {
+			if ctx.Err() != nil {
+				fmt.Fprintln(os.Stderr, "Upload interrupted, nothing has been stored at", p)
+				os.Exit(130)
+			}
+			log.Fatalf("could not upload to %s: %s", p, e.Error())
+		}
+	},
+}
+
+func init() {
+	putCmd.PersistentFlags().StringVar(&putContentType, "content-type", "", "MIME type of the uploaded file, detected from its extension or content by default")
+	putCmd.PersistentFlags().BoolVar(&rest.Encrypt, "encrypt", false, "Encrypt the content before it leaves this machine, see 'scp --help'")
+	addEncryptionFlags(putCmd)
+	addTransferFlags(putCmd)
+	RootCmd.AddCommand(putCmd)
+}
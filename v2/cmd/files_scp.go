@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -28,6 +33,24 @@ var scpFileExample = `
 3/ Download a file changing its name - remember: this will fail if a 'cat2.jpg' file already exists: 
   $ ` + os.Args[0] + ` scp cells://personal-files/funnyCat.jpg ./cat2.jpg
   Copying cells://personal-files/funnyCat.jpg to /home/pydio/downloads/
+
+4/ Download a folder as a zip archive:
+  $ ` + os.Args[0] + ` scp --archive=zip cells://personal-files/photos ./photos.zip
+  Downloading cells://personal-files/photos to /home/pydio/downloads/photos.zip
+
+5/ Upload the content of an archive in a remote folder:
+  $ ` + os.Args[0] + ` scp --extract ./bundle.tar.gz cells://common-files/bundle/
+  Extracting ./bundle.tar.gz to cells://common-files/bundle/
+
+6/ Copy a folder from a server to another one, both servers having been configured with 'configure --config <name>':
+  $ ` + os.Args[0] + ` scp cells@old://common-files/photos cells@new://common-files/
+  Copying cells@old://common-files/photos to cells@new://common-files/
+
+7/ Upload a folder with at most 1MB/s during office hours and 10MB/s the rest of the time, encrypting its files:
+  $ ` + os.Args[0] + ` scp --limit-schedule 08:00-18:00=1M --limit-rate 10M --encrypt ./backup cells://personal-files/
+
+8/ Upload files with user metadata and write a JSON report of the transfer:
+  $ ` + os.Args[0] + ` scp --meta classification=internal --tags finance,2021 --report-file report.json ./reports cells://common-files/
 `
 
 const (
@@ -35,9 +58,39 @@ const (
 	prefixB = "cells//"
 )
 
+// namedRemote matches remote paths on a server whose config has been stored under a name, e.g. cells@old://common-files.
+var namedRemote = regexp.MustCompile(`^cells@([\w.-]+)(:?//)`)
+
 var (
 	scpCurrentPrefix string
 	scpQuiet         bool
+	scpOnConflict    string
+	scpArchive       string
+	scpExtract       bool
+	scpReport        string
+	scpReportFile    string
+	scpMeta          []string
+	scpTags          []string
+	scpTagsNamespace string
+	scpMetaFile      string
+	scpContentType   string
+	// scpOut receives the messages of the command, it is the standard error when the report is printed on the standard output.
+	scpOut io.Writer = os.Stdout
+
+	transferExcludes      []string
+	transferIncludes      []string
+	transferIncludeHidden bool
+	transferSymlinks      string
+
+	transferParallel     int
+	transferThreshold    int64
+	transferPartSize     int64
+	transferConcurrency  int
+	transferLimitRate    string
+	transferRateSchedule []string
+	transferProgress     string
+	transferPassphrase   string
+	transferKeyFile      string
 )
 
 var scpFiles = &cobra.Command{
@@ -47,19 +100,53 @@ var scpFiles = &cobra.Command{
 Copy files from your local machine to your Pydio Cells server instance (and vice versa).
 
 To differentiate local from remote, prefix remote paths with 'cells://' or with 'cells//' (without the column) if you have installed the completion and intend to use it.
-For the time being, copy can only be performed with both different ends.
+To target a server that has been configured with 'configure --config <name>', use 'cells@<name>://' instead:
+files can then be streamed from one server to another, without being written on the local disk.
 
 Note that you can rename the file or base folder that you upload/download if:  
 
 - last part of the target path is a new name that *does not exists*,  
 - parent path exists and is a folder a target location.
+
+Hidden files and files matching the patterns listed in a '.cecignore' file at the root of the source folder are skipped (see --include-hidden, --exclude and --include).
+Interrupted transfers can be resumed by running the same command again, transient errors are retried automatically.
+The command exits with code 0 if all files have been transferred or skipped, 2 if some of them failed, 
+1 if none of them could be transferred, and 130 if it has been interrupted.
+See the flags below to tune transfers, verify, encrypt or tag files, and report on the result.
 `,
 	Args:    cobra.MinimumNArgs(2),
 	Example: scpFileExample,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		from, fromConf, e := parseRemoteArg(args[0])
+		if e != nil {
+			log.Fatal(e)
+		}
+		to, toConf, e := parseRemoteArg(args[1])
+		if e != nil {
+			log.Fatal(e)
+		}
+		// Each end talks to its own server, the default one being used if it is not named
+		srcCtx, targetCtx := ctx, ctx
+		if fromConf != nil {
+			srcCtx = WithConfig(ctx, fromConf)
+		}
+		if toConf != nil {
+			targetCtx = WithConfig(ctx, toConf)
+		}
 
-		from := args[0]
-		to := args[1]
+		if e := setReportOutput(); e != nil {
+			log.Fatal(e)
+		}
+		policy, e := ParseConflictPolicy(scpOnConflict)
+		if e != nil {
+			log.Fatal(e)
+		}
+		OnConflict = policy
+		if e := setTransferOptions(); e != nil {
+			log.Fatal(e)
+		}
 
 		if strings.HasPrefix(from, prefixA) || strings.HasPrefix(to, prefixA) {
 			scpCurrentPrefix = prefixA
@@ -69,75 +156,103 @@ Note that you can rename the file or base folder that you upload/download if:
 			// No prefix found
 			log.Fatal("Source and target are both local, copy remote to local or the opposite.")
 		}
+		// Use the same prefix on both ends when they are both remote
+		from, to = sameRemotePrefix(from), sameRemotePrefix(to)
+
+		if scpContentType != "" {
+			if ContentType, e = ParseContentType(scpContentType); e != nil {
+				log.Fatal(e)
+			}
+		}
+		if e := setEncryptionKey(); e != nil {
+			log.Fatal(e)
+		}
+		if UserMeta, e = NewUploadMeta(scpMeta, scpTags, scpTagsNamespace, scpMetaFile); e != nil {
+			log.Fatal(e)
+		}
+		if UserMeta != nil && !strings.HasPrefix(to, scpCurrentPrefix) {
+			log.Fatal("--meta, --tags and --meta-file only apply when the target is remote")
+		}
+
+		if scpArchive != "" {
+			downloadArchive(srcCtx, from, to)
+			return
+		}
+		if scpExtract {
+			extractArchive(targetCtx, from, to)
+			return
+		}
 
 		// Prepare paths
 		DryRun = false // Debug option
 		isSrcLocal := true
+		var isTargetRemote bool
 		var crawlerPath, targetPath string
 		var rename bool
 		var err error
 		if strings.HasPrefix(from, scpCurrentPrefix) {
 			// Download
 			isSrcLocal = false
-			var isRemote bool
 			crawlerPath = strings.TrimPrefix(from, scpCurrentPrefix)
-			targetPath, isRemote, rename, err = targetToFullPath(from, to)
+			targetPath, isTargetRemote, rename, err = targetToFullPath(targetCtx, from, to)
 			if err != nil {
 				log.Fatal(err)
 			}
-			if isRemote {
-				log.Fatal("Source and target are both remote, copy remote to local or the opposite.")
+			if isTargetRemote {
+				fmt.Fprintf(scpOut, "Copying %s to %s\n", args[0], args[1])
+			} else {
+				fmt.Fprintf(scpOut, "Downloading %s to %s\n", args[0], to)
 			}
-			fmt.Printf("Downloading %s to %s\n", from, to)
 		} else {
 			// Upload
 			targetPath = strings.TrimPrefix(to, scpCurrentPrefix)
 			// Check target path existence and handle rename corner cases
-			if _, _, rename, err = targetToFullPath(from, to); err != nil {
+			if _, _, rename, err = targetToFullPath(targetCtx, from, to); err != nil {
 				log.Fatal(err)
 			}
 			crawlerPath = from
-			fmt.Printf("Uploading %s to %s\n", from, to)
+			fmt.Fprintf(scpOut, "Uploading %s to %s\n", from, args[1])
 		}
 
-		crawler, e := NewCrawler(crawlerPath, isSrcLocal)
+		crawler, e := NewCrawler(srcCtx, crawlerPath, isSrcLocal)
 		if e != nil {
 			log.Fatal(e)
 		}
-		nn, e := crawler.Walk()
-		if e != nil {
+		if e := setTransferFilter(srcCtx, crawler); e != nil {
 			log.Fatal(e)
 		}
-
-		targetNode := NewTarget(targetPath, crawler, rename)
+		var targetNode *CrawlNode
+		if isTargetRemote {
+			targetNode = NewRemoteTarget(targetCtx, targetPath, crawler, rename)
+		} else {
+			targetNode = NewTarget(targetPath, crawler, rename)
+		}
 
 		refreshInterval := time.Millisecond * 10 // this is the default
 		if scpQuiet {
 			refreshInterval = time.Millisecond * 3000
 		}
-		pool := NewBarsPool(len(nn) > 1, len(nn), refreshInterval)
+		// Total number of nodes is updated while the source is walked
+		pool := NewBarsPool(crawler.IsDir, 1, refreshInterval)
+		pool.SetOut(scpOut)
 		pool.Start()
 
-		// CREATE FOLDERS
-		e = targetNode.MkdirAll(nn, pool)
-		if e != nil {
-			// Force stop of the pool that stays blocked otherwise:
-			// It is launched *before* the MkdirAll but only managed during the CopyAll phase.
-			pool.Stop()
-			log.Fatal(e)
+		// CREATE FOLDERS AND UPLOAD / DOWNLOAD FILES AS THEY ARE DISCOVERED
+		// Remote sources carry their own config, the context one is used for the target
+		errs := targetNode.Transfer(targetCtx, crawler, pool)
+		fmt.Fprintln(scpOut, "") // Add a line to reduce glitches in the terminal
+		writeReport(pool.Summary)
+		if ctx.Err() != nil {
+			exitInterrupted(pool.Summary)
 		}
-
-		// UPLOAD / DOWNLOAD FILES
-		errs := targetNode.CopyAll(nn, pool)
-		//pool.Stop()
-		if len(errs) > 0 {
-			log.Fatal(errs)
+		if pool.Summary.Ignored > 0 && scpReport == "" {
+			fmt.Fprint(scpOut, pool.Summary)
 		}
-		fmt.Println("") // Add a line to reduce glitches in the terminal
+		exitTransfer(pool.Summary, errs)
 	},
 }
 
-func targetToFullPath(from, to string) (string, bool, bool, error) {
+func targetToFullPath(ctx context.Context, from, to string) (string, bool, bool, error) {
 	var toPath string
 	//var isDir bool
 	var isRemote bool
@@ -146,7 +261,7 @@ func targetToFullPath(from, to string) (string, bool, bool, error) {
 		// This is remote: UPLOAD
 		isRemote = true
 		toPath = strings.TrimPrefix(to, scpCurrentPrefix)
-		_, ok := StatNode(toPath)
+		_, ok := StatNode(ctx, toPath)
 		if !ok {
 
 			parPath, _ := path.Split(toPath)
@@ -157,7 +272,7 @@ func targetToFullPath(from, to string) (string, bool, bool, error) {
 
 			// Check if parent exists. In such case, we rename the file or root folder that has been passed as local source
 			// Typically, `cec scp README.txt cells//common-files/readMe.md` or `cec scp local-folder cells//common-files/remote-folder`
-			if _, ok2 := StatNode(parPath); !ok2 {
+			if _, ok2 := StatNode(ctx, parPath); !ok2 {
 				// Target parent folder does not exist, we do not create it
 				return toPath, true, false, fmt.Errorf("Target parent folder %s does not exist on remote server. ", parPath)
 			} else {
@@ -197,9 +312,291 @@ func targetToFullPath(from, to string) (string, bool, bool, error) {
 	return toPath, isRemote, false, nil
 }
 
+// downloadArchive downloads a remote folder as a single archive file, that is built while the folder is walked.
+func downloadArchive(ctx context.Context, from, to string) {
+	format, e := ParseArchiveFormat(scpArchive)
+	if e != nil {
+		log.Fatal(e)
+	}
+	if !strings.HasPrefix(from, scpCurrentPrefix) || strings.HasPrefix(to, scpCurrentPrefix) {
+		log.Fatal("Archives can only be downloaded: source must be remote and target local.")
+	}
+	crawler, e := NewCrawler(ctx, strings.TrimPrefix(from, scpCurrentPrefix), false)
+	if e != nil {
+		log.Fatal(e)
+	}
+	if e := setTransferFilter(ctx, crawler); e != nil {
+		log.Fatal(e)
+	}
+
+	target, e := filepath.Abs(to)
+	if e != nil {
+		log.Fatal(e)
+	}
+	if i, e := os.Stat(target); e == nil && i.IsDir() {
+		target = filepath.Join(target, crawler.Base()+"."+string(format))
+	}
+	if _, e := os.Stat(target); e == nil {
+		switch OnConflict {
+		case ConflictOverwrite:
+		case ConflictSkip:
+			fmt.Fprintf(scpOut, "%s already exists, skipping\n", target)
+			return
+		default:
+			log.Fatalf("%s already exists", target)
+		}
+	}
+
+	// The archive is only renamed to its final name once it is complete
+	partFile := target + PartFileSuffix
+	f, e := os.Create(partFile)
+	if e != nil {
+		log.Fatal(e)
+	}
+	fmt.Fprintf(scpOut, "Downloading %s to %s\n", from, target)
+	refreshInterval := time.Millisecond * 10
+	if scpQuiet {
+		refreshInterval = time.Millisecond * 3000
+	}
+	pool := NewBarsPool(true, 1, refreshInterval)
+	pool.SetOut(scpOut)
+	pool.Start()
+	e = WriteArchive(ctx, crawler, format, f, pool)
+	fmt.Fprintln(scpOut, "")
+	if ce := f.Close(); e == nil {
+		e = ce
+	}
+	writeReport(pool.Summary)
+	if e != nil {
+		_ = os.Remove(partFile)
+		if ctx.Err() != nil {
+			exitInterrupted(pool.Summary)
+		}
+		log.Fatal(e)
+	}
+	if e := os.Rename(partFile, target); e != nil {
+		log.Fatal(e)
+	}
+}
+
+// extractArchive uploads the content of a local archive in a remote folder, each entry becoming a separate node.
+func extractArchive(ctx context.Context, from, to string) {
+	if strings.HasPrefix(from, scpCurrentPrefix) || !strings.HasPrefix(to, scpCurrentPrefix) {
+		log.Fatal("Archives can only be extracted while uploading: source must be a local archive and target a remote folder.")
+	}
+	if i, e := os.Stat(from); e != nil {
+		log.Fatal(e)
+	} else if i.IsDir() {
+		log.Fatalf("%s is a folder, only zip, tar and tar.gz archives can be extracted", from)
+	}
+	filter, e := NewFilter(transferExcludes, transferIncludes, transferIncludeHidden)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	targetPath := strings.Trim(strings.TrimPrefix(to, scpCurrentPrefix), "/")
+	fmt.Fprintf(scpOut, "Extracting %s to %s\n", from, to)
+	refreshInterval := time.Millisecond * 10
+	if scpQuiet {
+		refreshInterval = time.Millisecond * 3000
+	}
+	pool := NewBarsPool(true, 1, refreshInterval)
+	pool.SetOut(scpOut)
+	pool.Start()
+	errs := ExtractArchive(ctx, from, targetPath, filter, pool)
+	fmt.Fprintln(scpOut, "")
+	writeReport(pool.Summary)
+	if ctx.Err() != nil {
+		exitInterrupted(pool.Summary)
+	}
+	exitTransfer(pool.Summary, errs)
+}
+
+// setReportOutput validates the report flags. When the report is printed on the standard output,
+// messages and progress bars are displayed on the standard error so that the output can be parsed.
+func setReportOutput() error {
+	switch scpReport {
+	case "":
+	case "json":
+		scpOut = os.Stderr
+	default:
+		return fmt.Errorf("unknown report format %s, only json is supported", scpReport)
+	}
+	return nil
+}
+
+// writeReport prints the JSON report of the transfer and/or writes it to the report file, if required.
+func writeReport(summary *TransferSummary) {
+	if scpReport == "" && scpReportFile == "" {
+		return
+	}
+	data, e := json.MarshalIndent(summary, "", "  ")
+	if e != nil {
+		log.Println("could not build transfer report:", e)
+		return
+	}
+	if scpReport == "json" {
+		fmt.Println(string(data))
+	}
+	if scpReportFile != "" {
+		if e := ioutil.WriteFile(scpReportFile, data, 0644); e != nil {
+			log.Println("could not write transfer report:", e)
+		}
+	}
+}
+
+// parseRemoteArg loads the config of a remote path that targets a named server, and returns
+// the path with the usual prefix. The config is nil if the server is not named.
+func parseRemoteArg(arg string) (string, *CecConfig, error) {
+	m := namedRemote.FindStringSubmatch(arg)
+	if m == nil {
+		return arg, nil, nil
+	}
+	conf, e := LoadNamedConfig(m[1])
+	if e != nil {
+		return "", nil, e
+	}
+	return "cells" + m[2] + strings.TrimPrefix(arg, m[0]), conf, nil
+}
+
+// sameRemotePrefix rewrites a remote path with the prefix that is used for the current command.
+func sameRemotePrefix(p string) string {
+	if strings.HasPrefix(p, prefixA) {
+		return scpCurrentPrefix + strings.TrimPrefix(p, prefixA)
+	} else if strings.HasPrefix(p, prefixB) {
+		return scpCurrentPrefix + strings.TrimPrefix(p, prefixB)
+	}
+	return p
+}
+
+// setTransferFilter configures the crawler with the patterns passed on the command line and
+// the ones that are defined in the ignore file found at the root of the source folder.
+func setTransferFilter(ctx context.Context, crawler *CrawlNode) error {
+	policy, e := ParseSymlinkPolicy(transferSymlinks)
+	if e != nil {
+		return e
+	}
+	Symlinks = policy
+	f, e := NewFilter(transferExcludes, transferIncludes, transferIncludeHidden)
+	if e != nil {
+		return e
+	}
+	if e := f.LoadIgnoreFile(ctx, crawler); e != nil {
+		return e
+	}
+	crawler.Filter = f
+	return nil
+}
+
+// setTransferOptions applies the tuning options of the config file, then the ones passed on the command line,
+// to the rest package.
+func setTransferOptions() error {
+	if DefaultConfig != nil && DefaultConfig.Transfers != nil {
+		if e := ApplyTransferSettings(*DefaultConfig.Transfers); e != nil {
+			return fmt.Errorf("invalid transfer settings in config file: %s", e.Error())
+		}
+	}
+	if e := ApplyTransferSettings(TransferSettings{
+		ParallelFiles:      transferParallel,
+		MultipartThreshold: transferThreshold,
+		PartSize:           transferPartSize,
+		PartsConcurrency:   transferConcurrency,
+	}); e != nil {
+		return e
+	}
+
+	rate, e := ParseRate(transferLimitRate)
+	if e != nil {
+		return e
+	}
+	schedule, e := ParseRateSchedule(transferRateSchedule)
+	if e != nil {
+		return e
+	}
+	SetRateLimit(rate, schedule)
+
+	if transferProgress != "" {
+		mode, e := ParseProgressMode(transferProgress)
+		if e != nil {
+			return e
+		}
+		ProgressDisplay = mode
+	}
+	return nil
+}
+
+// addTransferFlags registers the flags that tune multipart transfers.
+func addTransferFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.IntVar(&transferParallel, "parallel", 0, "Number of files that are transferred in parallel (default 3, or \"parallelFiles\" in the \"transfers\" section of the config)")
+	flags.Int64Var(&transferThreshold, "multipart-threshold", 0, "Size in MB from which files are uploaded by parts, uploads of such files being resumable (default 100, or \"multipartThreshold\" in the config)")
+	flags.Int64Var(&transferPartSize, "part-size", 0, "Size in MB of the parts of big files that are transferred in parallel, between 5 and 5120, increased if needed to stay under 10,000 parts (default 50, or \"partSize\" in the config)")
+	flags.IntVar(&transferConcurrency, "parts-concurrency", 0, "Number of parts of a same file that are transferred in parallel (default 3, or \"partsConcurrency\" in the config)")
+	flags.StringVar(&transferLimitRate, "limit-rate", "", "Maximum bandwidth used by all transfers together, e.g. 500K or 10M (per second)")
+	flags.StringArrayVar(&transferRateSchedule, "limit-schedule", []string{}, "Bandwidth limit that applies during a daily time window instead of --limit-rate, e.g. 08:00-18:00=1M, 0 meaning unlimited (can be repeated)")
+	flags.StringVar(&transferProgress, "progress", string(ProgressAuto), "How to display the progress of transfers: auto, bars or log (one line per file, used by default when the output is not a terminal)")
+}
+
+// addEncryptionFlags registers the flags that define the key used to encrypt uploads and decrypt downloads.
+func addEncryptionFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&transferPassphrase, "passphrase-file", "", "File containing the passphrase that encrypts and decrypts files (or set "+EncryptionPassphraseEnv+")")
+	flags.StringVar(&transferKeyFile, "key-file", "", "Key file that encrypts and decrypts files, instead of the key stored with 'configure encryption'")
+}
+
+// setEncryptionKey loads the key passed on the command line or in the environment. Otherwise, the key stored
+// in the keyring, if any, is used when needed.
+func setEncryptionKey() error {
+	var e error
+	if transferPassphrase != "" {
+		var data []byte
+		if data, e = ioutil.ReadFile(transferPassphrase); e != nil {
+			return e
+		}
+		Encryption, e = NewPassphraseKey(strings.TrimRight(string(data), "\r\n"))
+	} else if transferKeyFile != "" {
+		var data []byte
+		if data, e = ioutil.ReadFile(transferKeyFile); e != nil {
+			return e
+		}
+		Encryption, e = NewFileKey(data)
+	} else if passphrase := os.Getenv(EncryptionPassphraseEnv); passphrase != "" {
+		Encryption, e = NewPassphraseKey(passphrase)
+	}
+	if e != nil {
+		return e
+	}
+	return CheckEncryptionKey()
+}
+
+// addFilterFlags registers the flags that are shared by all commands that walk trees.
+func addFilterFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringArrayVar(&transferExcludes, "exclude", []string{}, "Skip files and folders matching this gitignore-style pattern, in addition to the ones of the '.cecignore' file (can be repeated)")
+	flags.StringArrayVar(&transferIncludes, "include", []string{}, "Process files and folders matching this pattern, even if they are excluded by another rule (can be repeated)")
+	flags.BoolVar(&transferIncludeHidden, "include-hidden", false, "Also process files and folders whose name starts with a dot")
+	flags.StringVar(&transferSymlinks, "symlinks", string(SymlinksFollow), "What to do with local symbolic links: follow (links to a parent folder are skipped), skip or error. Special files are always skipped")
+}
+
 func init() {
 
 	flags := scpFiles.PersistentFlags()
 	flags.BoolVarP(&scpQuiet, "quiet", "q", false, "Reduce the amount of logs")
+	flags.StringVar(&scpOnConflict, "on-conflict", string(ConflictOverwrite), "What to do when a file already exists at target path: skip it, overwrite it, rename the new file ('name-1.ext'), overwrite it if the source is newer, or fail for this file")
+	flags.StringVar(&scpArchive, "archive", "", "Download a remote folder as a single archive file built on the fly, named after the folder if the target is a folder: zip or tar.gz")
+	flags.BoolVar(&scpExtract, "extract", false, "Upload each entry of a local zip, tar or tar.gz archive as a separate node of the remote folder, without extracting it locally")
+	flags.BoolVar(&VerifyTransfers, "verify", false, "Compare the MD5 and SHA-256 hashes of transferred files with the ETag and metadata of the remote files and retry on mismatch")
+	flags.StringVar(&scpReport, "report", "", "Print a report of the transfer of each file (source, target, size, duration, status and error) on the standard output when it is over, other messages going to the standard error: json")
+	flags.StringVar(&scpReportFile, "report-file", "", "Write a JSON report of the transfer of each file to this file")
+	flags.BoolVar(&PreserveTimes, "preserve-times", false, "Keep the modification time of the transferred files, stored in the metadata of uploaded objects")
+	flags.StringArrayVar(&scpMeta, "meta", []string{}, "Set a user metadata on each uploaded file once it is indexed, e.g. classification=internal, the 'usermeta-' prefix being optional (can be repeated)")
+	flags.StringSliceVar(&scpTags, "tags", []string{}, "Comma separated tags to set on each uploaded file")
+	flags.StringVar(&scpTagsNamespace, "tags-namespace", DefaultTagsNamespace, "User metadata namespace that stores the tags")
+	flags.BoolVar(&Encrypt, "encrypt", false, "Encrypt uploaded files with AES-256-GCM before they leave this machine, using a random data key per file that is encrypted with your key. Encrypted uploads cannot be resumed")
+	flags.StringVar(&scpContentType, "content-type", "", "MIME type of the uploaded files, detected from their extension or content by default")
+	flags.StringVar(&scpMetaFile, "meta-file", "", "JSON file mapping the paths of uploaded files, relative to the source, to their user metadata, e.g. {\"a/report.pdf\": {\"tags\": [\"finance\"]}}")
+	addEncryptionFlags(scpFiles)
+	addFilterFlags(scpFiles)
+	addTransferFlags(scpFiles)
 	RootCmd.AddCommand(scpFiles)
 }
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pydio/cells-client/v2/rest"
+)
+
+var syncExample = `
+1/ Mirror a local folder into an existing workspace folder:
+  $ ` + os.Args[0] + ` sync ./photos cells://common-files/photos
+  Synchronizing ./photos to cells://common-files/photos
+
+2/ Mirror a remote folder locally and remove local files that are not on the server anymore:
+  $ ` + os.Args[0] + ` sync --delete cells://personal-files/reports ./reports
+
+3/ Keep a local folder and a remote folder in sync, changes being propagated in both directions:
+  $ ` + os.Args[0] + ` sync --bidirectional ./reports cells://personal-files/reports
+`
+
+var (
+	syncDelete        bool
+	syncBidirectional bool
+	syncQuiet         bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: `Synchronize a local folder with a folder on Cells`,
+	Long: `
+Mirror the content of a source folder into a target folder, one end being local and the other one on your Cells server.
+As with scp, use 'cells@<name>://' to target a server that has been configured with 'configure --config <name>'.
+
+Unlike scp, only the files that are missing or have changed on the target are transferred.
+Files are compared using their size and modification time; when the size is the same but the source is newer,
+the MD5 hash of the local file is compared with the ETag of the remote file before deciding to transfer it.
+
+The content of the source folder is synchronized directly *inside* the target folder, that is created if necessary.
+With the --delete flag, files and folders of the target that do not exist in the source are removed
+(remote nodes are moved to the recycle bin).
+
+As with scp, hidden files, files matching --exclude patterns and files listed in the '.cecignore' file 
+of the source folder are ignored on both sides: they are neither transferred nor deleted.
+Local symbolic links are handled according to --symlinks, as with scp. Remote files that have been encrypted 
+with 'scp --encrypt' are decrypted when they are downloaded, using the key passed with --passphrase-file or --key-file.
+Transfers can be tuned with the same flags as scp, e.g. --parallel, --part-size, --limit-rate or --progress.
+Use --preserve-times so that downloaded files keep the modification time of the remote files: otherwise they 
+are considered modified at the time of the download.
+
+With the --bidirectional flag, the state of both folders at the end of the synchronization is stored locally:
+on next run, creations, modifications and deletions that happened on either side since then are propagated
+to the other side. Files that have been modified on both sides are not overwritten: the local version is 
+renamed into a conflict copy (e.g. 'report-conflict-20210218-153000.pdf') that is uploaded next to the remote version.
+`,
+	Args:    cobra.ExactArgs(2),
+	Example: syncExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		from, fromConf, e := parseRemoteArg(args[0])
+		if e != nil {
+			log.Fatal(e)
+		}
+		to, toConf, e := parseRemoteArg(args[1])
+		if e != nil {
+			log.Fatal(e)
+		}
+		if fromConf != nil {
+			ctx = rest.WithConfig(ctx, fromConf)
+		} else if toConf != nil {
+			ctx = rest.WithConfig(ctx, toConf)
+		}
+		if e := setTransferOptions(); e != nil {
+			log.Fatal(e)
+		}
+		if e := setEncryptionKey(); e != nil {
+			log.Fatal(e)
+		}
+
+		var prefix string
+		if strings.HasPrefix(from, prefixA) || strings.HasPrefix(to, prefixA) {
+			prefix = prefixA
+		} else if strings.HasPrefix(from, prefixB) || strings.HasPrefix(to, prefixB) {
+			prefix = prefixB
+		} else {
+			log.Fatal("Source and target are both local, synchronize remote to local or the opposite.")
+		}
+		isSrcLocal := !strings.HasPrefix(from, prefix)
+		if !isSrcLocal && strings.HasPrefix(to, prefix) {
+			log.Fatal("Source and target are both remote, synchronize remote to local or the opposite.")
+		}
+
+		var crawlerPath, targetPath string
+		if isSrcLocal {
+			crawlerPath = from
+			targetPath = strings.Trim(strings.TrimPrefix(to, prefix), "/")
+		} else {
+			crawlerPath = strings.Trim(strings.TrimPrefix(from, prefix), "/")
+			var e error
+			if targetPath, e = filepath.Abs(to); e != nil {
+				log.Fatal(e)
+			}
+		}
+
+		if syncBidirectional {
+			if isSrcLocal {
+				syncBothWays(ctx, crawlerPath, targetPath)
+			} else {
+				syncBothWays(ctx, targetPath, crawlerPath)
+			}
+			return
+		}
+
+		crawler, e := rest.NewCrawler(ctx, crawlerPath, isSrcLocal)
+		if e != nil {
+			log.Fatal(e)
+		}
+		if !crawler.IsDir {
+			log.Fatalf("%s is not a folder, only folders can be synchronized. Rather use scp to copy single files.", from)
+		}
+		if e := setTransferFilter(ctx, crawler); e != nil {
+			log.Fatal(e)
+		}
+		// Source content goes directly inside the target folder
+		targetNode := rest.NewTarget(targetPath, crawler, true)
+
+		fmt.Printf("Synchronizing %s to %s\n", from, to)
+		diff, e := rest.ComputeSyncDiff(ctx, crawler, targetNode)
+		if e != nil {
+			log.Fatal(e)
+		}
+
+		// Files that could not be transferred do not prevent the removal of extraneous files
+		summary, errs := syncTransfer(ctx, targetNode, crawler, diff.ToTransfer)
+		if syncDelete && len(diff.ToDelete) > 0 {
+			fmt.Printf("Removing %d extraneous file(s) or folder(s) from target\n", len(diff.ToDelete))
+			if e := targetNode.DeleteAll(ctx, diff.ToDelete); e != nil {
+				errs = append(errs, e)
+			}
+		}
+
+		if len(diff.ToTransfer) == 0 && (!syncDelete || len(diff.ToDelete) == 0) {
+			fmt.Println("Source and target are already in sync, nothing to do")
+		}
+		if summary == nil {
+			summary = &rest.TransferSummary{}
+		}
+		exitTransfer(summary, errs)
+	},
+}
+
+// syncBothWays reconciles a local and a remote folder using the state stored at the end of the previous run.
+func syncBothWays(ctx context.Context, localPath, remotePath string) {
+	if e := os.MkdirAll(localPath, 0755); e != nil {
+		log.Fatal(e)
+	}
+	local, e := rest.NewCrawler(ctx, localPath, true)
+	if e != nil {
+		log.Fatal(e)
+	}
+	if !local.IsDir {
+		log.Fatalf("%s is not a folder, only folders can be synchronized.", localPath)
+	}
+	remote, e := rest.EnsureRemoteFolder(ctx, remotePath)
+	if e != nil {
+		log.Fatal(e)
+	}
+	// Rules defined in the local ignore file apply on both sides
+	if e := setTransferFilter(ctx, local); e != nil {
+		log.Fatal(e)
+	}
+	remote.Filter = local.Filter
+	state, e := rest.LoadSyncState(ctx, local.FullPath, remote.FullPath)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	fmt.Printf("Synchronizing %s and %s in both directions\n", local.FullPath, remote.FullPath)
+	diff, e := rest.ComputeBidirectionalDiff(ctx, local, remote, state)
+	if e != nil {
+		log.Fatal(e)
+	}
+	if e := diff.RenameConflicts(); e != nil {
+		log.Fatal(e)
+	}
+	for _, c := range diff.Conflicts {
+		fmt.Printf("Conflict detected, local version has been renamed to %s\n", c.Copy.FullPath)
+	}
+
+	// Nodes that could not be synchronized keep their previous state and will be handled again on next run.
+	var errs []error
+	var failed []*rest.CrawlNode
+	pushed, ee := syncTransfer(ctx, rest.NewTarget(remote.FullPath, local, true), local, diff.Push)
+	errs = append(errs, ee...)
+	failed = append(failed, pushed.Unfinished(diff.Push)...)
+	pulled, ee := syncTransfer(ctx, rest.NewTarget(local.FullPath, remote, true), remote, diff.Pull)
+	errs = append(errs, ee...)
+	failed = append(failed, pulled.Unfinished(diff.Pull)...)
+	if e := rest.NewTarget(remote.FullPath, local, true).DeleteAll(ctx, diff.DeleteRemote); e != nil {
+		errs = append(errs, e)
+		failed = append(failed, diff.DeleteRemote...)
+	}
+	if e := rest.NewTarget(local.FullPath, remote, true).DeleteAll(ctx, diff.DeleteLocal); e != nil {
+		errs = append(errs, e)
+		failed = append(failed, diff.DeleteLocal...)
+	}
+
+	if !rest.DryRun {
+		if e := state.Update(ctx, local, remote, failed); e != nil {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) > 0 {
+		log.Fatal(errs)
+	}
+
+	if len(diff.Push)+len(diff.Pull)+len(diff.DeleteLocal)+len(diff.DeleteRemote) == 0 {
+		fmt.Println("Both folders are already in sync, nothing to do")
+	}
+}
+
+// syncTransfer creates the folders and copies the files of the passed list to the target using the scp pipeline.
+// Entries that have been ignored while walking the source are listed at the end.
+// It returns the summary of the transfer, that is nil if there was nothing to transfer nor to report.
+func syncTransfer(ctx context.Context, targetNode, source *rest.CrawlNode, nn []*rest.CrawlNode) (*rest.TransferSummary, []error) {
+	if len(nn) == 0 && len(source.Ignored) == 0 {
+		return nil, nil
+	}
+	refreshInterval := time.Millisecond * 10 // this is the default
+	if syncQuiet {
+		refreshInterval = time.Millisecond * 3000
+	}
+	pool := rest.NewBarsPool(len(nn) > 1, len(nn), refreshInterval)
+	pool.Start()
+	if e := targetNode.MkdirAll(ctx, nn, pool); e != nil {
+		pool.Stop()
+		return pool.Summary, []error{e}
+	}
+	errs := targetNode.CopyAll(ctx, source, nn, pool)
+	fmt.Println("")
+	if ctx.Err() != nil {
+		exitInterrupted(pool.Summary)
+	}
+	if pool.Summary.Ignored > 0 || pool.Summary.ExitCode() != 0 {
+		fmt.Print(pool.Summary)
+	}
+	return pool.Summary, errs
+}
+
+func init() {
+	flags := syncCmd.PersistentFlags()
+	flags.BoolVarP(&syncDelete, "delete", "d", false, "Remove files and folders of the target that do not exist in the source")
+	flags.BoolVarP(&syncBidirectional, "bidirectional", "b", false, "Propagate changes in both directions, using the state stored at the end of the previous run")
+	flags.BoolVarP(&syncQuiet, "quiet", "q", false, "Reduce the amount of logs")
+	flags.BoolVar(&rest.VerifyTransfers, "verify", false, "Compare checksums of transferred files with the ones of the remote files and retry on mismatch")
+	flags.BoolVar(&rest.PreserveTimes, "preserve-times", false, "Keep the modification time of the transferred files")
+	addEncryptionFlags(syncCmd)
+	addFilterFlags(syncCmd)
+	addTransferFlags(syncCmd)
+	RootCmd.AddCommand(syncCmd)
+}
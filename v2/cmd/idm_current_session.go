@@ -16,8 +16,9 @@ var currentSession = &cobra.Command{
 	Long:  `Tmp command to retrieve the registry for your current connection to your Pydio Cells instance.`,
 
 	Run: func(cm *cobra.Command, args []string) {
+		ctx := cm.Context()
 		uri := "/a/frontend/state"
-		resp, err := rest.AuthenticatedGet(uri)
+		resp, err := rest.AuthenticatedGet(ctx, uri)
 		if err != nil {
 			fmt.Printf("could retrieve state: %s\n", err.Error())
 			log.Fatal(err)
@@ -15,8 +15,9 @@ var listGroups = &cobra.Command{
 	Short: "List groups",
 	Long:  `List the groups defined in your Pydio Cells instance.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
-		ctx, apiClient, err := rest.GetApiClient()
+		_, apiClient, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err)
 		}
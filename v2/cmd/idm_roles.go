@@ -18,8 +18,9 @@ var listRoles = &cobra.Command{
 including technical roles that are implicitely created upon user and group creation.
 `,
 	Run: func(cm *cobra.Command, args []string) {
+		ctx := cm.Context()
 
-		ctx, apiClient, err := rest.GetApiClient()
+		_, apiClient, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err)
 		}
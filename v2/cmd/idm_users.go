@@ -15,8 +15,9 @@ var listUsers = &cobra.Command{
 	Short: "List users",
 	Long:  `List the users defined in your Pydio Cells instance.`,
 	Run: func(cm *cobra.Command, args []string) {
+		ctx := cm.Context()
 
-		ctx, apiClient, err := rest.GetApiClient()
+		_, apiClient, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err)
 		}
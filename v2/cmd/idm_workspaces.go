@@ -18,8 +18,9 @@ var listWorkspaces = &cobra.Command{
 List all the workspaces on which the current logged in user (configured via the oauth command) has at least Read Access
 `,
 	Run: func(cm *cobra.Command, args []string) {
+		ctx := cm.Context()
 
-		ctx, apiClient, err := rest.GetApiClient()
+		_, apiClient, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err)
 		}
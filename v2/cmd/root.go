@@ -4,8 +4,12 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
@@ -34,6 +38,16 @@ This will guide you through a quick procedure to get you up and ready in no time
 `,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 
+		if rest.Retry.Retries < 0 {
+			log.Fatal("--retries must be a positive number")
+		}
+
+		// A bare name designates a config that has been stored with 'configure --config name'
+		configFile = rest.ResolveConfigPath(configFile)
+		if configFile != "" {
+			rest.SetConfigFilePath(configFile)
+		}
+
 		switch os.Args[1] {
 		// These command and respective children do not need an already configured environment
 		case "help", "configure", "version", "completion", "oauth", "clear", "doc", "update", "token":
@@ -51,9 +65,53 @@ This will guide you through a quick procedure to get you up and ready in no time
 	},
 }
 
+// CancelOnInterrupt returns a context that is cancelled when the process receives SIGINT or SIGTERM,
+// so that ongoing transfers can stop cleanly. A second signal terminates the process immediately.
+func CancelOnInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigs:
+			fmt.Fprintln(os.Stderr, "\nInterrupting, please wait while ongoing requests are cancelled (press Ctrl-C again to force exit)")
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(sigs)
+			return
+		}
+		<-sigs
+		os.Exit(130)
+	}()
+	return ctx, cancel
+}
+
+// exitTransfer prints the errors of a transfer, if any, and exits with 1 if nothing could be transferred
+// or 2 if the transfer only partially failed. It returns when the transfer has been successful.
+func exitTransfer(summary *rest.TransferSummary, errs []error) {
+	code := summary.ExitCode()
+	if len(errs) > 0 {
+		log.Println(errs)
+		if code == 0 && summary.Transferred+summary.Skipped == 0 {
+			code = 1
+		} else if code == 0 {
+			code = 2
+		}
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// exitInterrupted prints what has and has not been transferred before exiting after an interruption.
+func exitInterrupted(summary *rest.TransferSummary) {
+	fmt.Fprintf(os.Stderr, "Transfer interrupted: %s", summary)
+	os.Exit(130)
+}
+
 var bashCompletionFunc = `__` + os.Args[0] + `_custom_func() {
   case ${last_command} in
-  ` + os.Args[0] + `_mv | ` + os.Args[0] + `_cp | ` + os.Args[0] + `_rm | ` + os.Args[0] + `_ls)
+  ` + os.Args[0] + `_mv | ` + os.Args[0] + `_cp | ` + os.Args[0] + `_rm | ` + os.Args[0] + `_ls | ` + os.Args[0] + `_cat | ` + os.Args[0] + `_put)
     _path_completion
     return
     ;;
@@ -61,7 +119,7 @@ var bashCompletionFunc = `__` + os.Args[0] + `_custom_func() {
     _datasources_completion
     return
     ;;
-  ` + os.Args[0] + `_scp)
+  ` + os.Args[0] + `_scp | ` + os.Args[0] + `_sync)
     _scp_path_completion
     return
     ;;
@@ -135,5 +193,6 @@ _datasources_completion() {
 
 func init() {
 	flags := RootCmd.PersistentFlags()
-	flags.StringVarP(&configFile, "config", "c", "", "Path to the configuration file")
+	flags.StringVarP(&configFile, "config", "c", "", "Path to the configuration file, or name of a config stored with 'configure --config name'")
+	flags.IntVar(&rest.Retry.Retries, "retries", rest.Retry.Retries, "Number of times a request that failed with a transient error (server error, throttling, reset connection or timeout) is sent again, 0 to disable retries")
 }
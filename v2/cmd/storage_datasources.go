@@ -22,9 +22,10 @@ var listDatasources = &cobra.Command{
 	Short: "List configured datasources",
 	Long:  `List all the datasources`,
 	Run: func(cm *cobra.Command, args []string) {
+		ctx := cm.Context()
 
 		//connects to the pydio api via the sdkConfig
-		ctx, apiClient, err := rest.GetApiClient()
+		_, apiClient, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err.Error())
 		}
@@ -65,13 +66,14 @@ var resyncDs = &cobra.Command{
 	Short: "Launch a resync",
 	Long:  `Launch a resync job on the specified datasource`,
 	Run: func(cm *cobra.Command, args []string) {
+		ctx := cm.Context()
 
 		if len(args) != 1 {
 			log.Fatal(fmt.Errorf("please provide the name of the datasource to resync"))
 		}
 		dsName := args[0]
 
-		ctx, client, err := rest.GetApiClient()
+		_, client, err := rest.GetApiClient()
 		if err != nil {
 			log.Fatal(err.Error())
 		}
@@ -8,6 +8,7 @@ require (
 	github.com/dustin/go-humanize v1.0.0
 	github.com/fatih/color v1.10.0
 	github.com/go-log/log v0.2.0 // indirect
+	github.com/go-openapi/runtime v0.19.24
 	github.com/go-openapi/strfmt v0.20.0
 	github.com/go-openapi/validate v0.20.2 // indirect
 	github.com/google/go-cmp v0.5.4 // indirect
@@ -36,6 +37,7 @@ require (
 	github.com/stretchr/objx v0.3.0 // indirect
 	github.com/tidwall/pretty v1.0.2 // indirect
 	github.com/zalando/go-keyring v0.1.1
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/sys v0.0.0-20210124154548-22da62e12c0c // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/pydio/cells-client/v2/cmd"
 	"github.com/pydio/cells-client/v2/common"
 )
@@ -8,5 +10,7 @@ import (
 func main() {
 	common.PackageType = "CellsClient"
 	common.PackageLabel = "Cells Client"
-	cmd.RootCmd.Execute()
+	ctx, cancel := cmd.CancelOnInterrupt(context.Background())
+	defer cancel()
+	cmd.RootCmd.ExecuteContext(ctx)
 }
@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gosuri/uiprogress"
+)
+
+// ArchiveFormat is the format of the archives that are built when downloading a folder as a single file.
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+// ParseArchiveFormat validates the format passed on the command line.
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch f := ArchiveFormat(strings.ToLower(s)); f {
+	case ArchiveZip, ArchiveTarGz:
+		return f, nil
+	case "tgz":
+		return ArchiveTarGz, nil
+	}
+	return "", fmt.Errorf("unknown archive format %s, use zip or tar.gz", s)
+}
+
+// archiveWriter abstracts the zip and tar formats.
+type archiveWriter interface {
+	addFolder(name string, n *CrawlNode) error
+	addFile(name string, n *CrawlNode) (io.Writer, error)
+	Close() error
+}
+
+// WriteArchive walks the remote source and writes its folders and files in an archive of the passed format,
+// as soon as they are discovered: files are downloaded one after the other and directly streamed to the
+// writer, so that neither the archive nor its content is stored in memory or on the local disk.
+func WriteArchive(ctx context.Context, source *CrawlNode, format ArchiveFormat, w io.Writer, pool *BarsPool) error {
+	var aw archiveWriter
+	if format == ArchiveZip {
+		aw = &zipArchive{Writer: zip.NewWriter(w)}
+	} else {
+		gz := gzip.NewWriter(w)
+		aw = &tarArchive{Writer: tar.NewWriter(gz), gz: gz}
+	}
+
+	idx := -1
+	pool.startDiscovery()
+	e := source.WalkFunc(ctx, func(n *CrawlNode) error {
+		pool.discovered(n)
+		defer pool.Done()
+		name := n.RelPath
+		if source.IsDir {
+			name = path.Join(source.Base(), strings.TrimPrefix(n.RelPath, "/"))
+		}
+		if n.IsDir {
+			return aw.addFolder(name, n)
+		}
+
+		idx++
+		barSize := n.Size
+		if barSize == 0 {
+			barSize = 1
+		}
+		bar := pool.Get(idx, int(barSize), n.Base())
+		defer pool.fileDone(bar)
+		report := newFileReport(n, name)
+		e := archiveFile(n.context(ctx), aw, name, n, bar)
+		if e != nil {
+			pool.Summary.failed(report, e, ctx.Err() != nil)
+			return fmt.Errorf("could not add %s to the archive: %s", n.FullPath, e.Error())
+		}
+		bar.Set(bar.Total)
+		pool.Summary.transferred(report)
+		return nil
+	})
+	pool.stopDiscovery()
+	pool.Stop()
+	if e != nil {
+		_ = aw.Close()
+		return e
+	}
+	return aw.Close()
+}
+
+// archiveFile downloads a remote file in a new entry of the archive.
+func archiveFile(ctx context.Context, aw archiveWriter, name string, n *CrawlNode, bar *uiprogress.Bar) error {
+	reader, size, e := OpenFile(ctx, n.FullPath)
+	if e != nil {
+		return e
+	}
+	defer reader.Close()
+	if size != n.Size {
+		// Encrypted file: the entry holds the decrypted content
+		plain := *n
+		plain.Size = size
+		n = &plain
+		bar.Total = int(size)
+	}
+	entry, e := aw.addFile(name, n)
+	if e != nil {
+		return e
+	}
+	read, e := io.Copy(entry, &PgReader{Reader: reader, bar: bar, total: int(n.Size)})
+	if e != nil {
+		return e
+	}
+	if read != n.Size {
+		return fmt.Errorf("received %d bytes, expected %d", read, n.Size)
+	}
+	return nil
+}
+
+type zipArchive struct {
+	*zip.Writer
+}
+
+func (z *zipArchive) addFolder(name string, n *CrawlNode) error {
+	h := &zip.FileHeader{Name: name + "/", Modified: n.MTime}
+	h.SetMode(os.ModeDir | 0755)
+	_, e := z.CreateHeader(h)
+	return e
+}
+
+func (z *zipArchive) addFile(name string, n *CrawlNode) (io.Writer, error) {
+	h := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: n.MTime, UncompressedSize64: uint64(n.Size)}
+	h.SetMode(0644)
+	return z.CreateHeader(h)
+}
+
+type tarArchive struct {
+	*tar.Writer
+	gz *gzip.Writer
+}
+
+func (t *tarArchive) addFolder(name string, n *CrawlNode) error {
+	return t.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0755, ModTime: n.MTime})
+}
+
+func (t *tarArchive) addFile(name string, n *CrawlNode) (io.Writer, error) {
+	if e := t.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: n.Size, ModTime: n.MTime}); e != nil {
+		return nil, e
+	}
+	return t.Writer, nil
+}
+
+func (t *tarArchive) Close() error {
+	if e := t.Writer.Close(); e != nil {
+		return e
+	}
+	return t.gz.Close()
+}
@@ -3,6 +3,7 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -10,8 +11,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 
+	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
 	"github.com/shibukawa/configdir"
 
@@ -33,6 +36,48 @@ var (
 type CecConfig struct {
 	cells_sdk.SdkConfig
 	SkipKeyring bool
+	Transfers   *TransferSettings `json:"transfers,omitempty"`
+
+	// filePath is the file where the config is stored when its token is refreshed, if not the current config file.
+	// It also identifies the secrets of the config in the keyring.
+	filePath string
+}
+
+type configKey struct{}
+
+// WithConfig returns a context that makes the calls of this package use the passed config instead of DefaultConfig.
+func WithConfig(ctx context.Context, conf *CecConfig) context.Context {
+	return context.WithValue(ctx, configKey{}, conf)
+}
+
+// configFrom returns the config carried by the context, or DefaultConfig.
+func configFrom(ctx context.Context) *CecConfig {
+	if c, ok := ctx.Value(configKey{}).(*CecConfig); ok && c != nil {
+		return c
+	}
+	return DefaultConfig
+}
+
+// ResolveConfigPath converts a config name to the path of the corresponding file in the default config folder,
+// e.g. "old" to ".../cells-client/old.json". Paths to existing files and paths with a folder are kept as is.
+func ResolveConfigPath(nameOrPath string) string {
+	if nameOrPath == "" || strings.ContainsAny(nameOrPath, `/\`) || strings.HasSuffix(nameOrPath, ".json") {
+		return nameOrPath
+	}
+	if _, e := os.Stat(nameOrPath); e == nil {
+		return nameOrPath
+	}
+	return filepath.Join(filepath.Dir(DefaultConfigFilePath()), nameOrPath+".json")
+}
+
+// LoadNamedConfig loads a config that has been stored under the given name with 'configure --config name'.
+func LoadNamedConfig(name string) (*CecConfig, error) {
+	p := ResolveConfigPath(name)
+	c, e := loadConfigFile(p)
+	if e != nil {
+		return nil, fmt.Errorf("cannot load config %s, please make sure to run 'configure --config %s' first (Error: %s)", name, name, e.Error())
+	}
+	return c, nil
 }
 
 func GetConfigFilePath() string {
@@ -77,32 +122,46 @@ func GetApiClient(anonymous ...bool) (context.Context, *client.PydioCellsRest, e
 	if len(anonymous) > 0 && anonymous[0] {
 		anon = true
 	}
-	DefaultConfig.CustomHeaders = map[string]string{"User-Agent": "cells-client/" + common.Version}
-	c, t, e := transport.GetRestClientTransport(&DefaultConfig.SdkConfig, anon)
+	return apiClientFor(DefaultConfig, anon)
+}
+
+// getApiClient returns a client for the server whose config is carried by the context.
+func getApiClient(ctx context.Context) (*client.PydioCellsRest, error) {
+	_, cl, e := apiClientFor(configFrom(ctx), false)
+	return cl, e
+}
+
+func apiClientFor(conf *CecConfig, anon bool) (context.Context, *client.PydioCellsRest, error) {
+	conf.CustomHeaders = map[string]string{"User-Agent": "cells-client/" + common.Version}
+	c, t, e := transport.GetRestClientTransport(&conf.SdkConfig, anon)
 	if e != nil {
 		return nil, nil, e
 	}
+	if rt, ok := t.(*httptransport.Runtime); ok {
+		rt.Transport = newRetryTransport(rt.Transport)
+	}
 	cl := client.New(t, strfmt.Default)
 	return c, cl, nil
-
 }
 
 // AuthenticatedGet performs an authenticated GET request for the passed URI (that must start with a '/')
-func AuthenticatedGet(uri string) (*http.Response, error) {
+func AuthenticatedGet(ctx context.Context, uri string) (*http.Response, error) {
 
-	currURL := DefaultConfig.SdkConfig.Url + uri
-	req, err := http.NewRequest("GET", currURL, nil)
+	conf := configFrom(ctx)
+	currURL := conf.SdkConfig.Url + uri
+	req, err := http.NewRequestWithContext(ctx, "GET", currURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	token, err := oidc.RetrieveToken(&DefaultConfig.SdkConfig)
+	token, err := oidc.RetrieveToken(&conf.SdkConfig)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
-	httpClient := sdk_http.GetHttpClient(&DefaultConfig.SdkConfig)
+	httpClient := sdk_http.GetHttpClient(&conf.SdkConfig)
+	httpClient.Transport = newRetryTransport(httpClient.Transport)
 	return httpClient.Do(req)
 }
 
@@ -125,31 +184,11 @@ func SetUpEnvironment(confPath string) error {
 	}
 
 	if c.Url == "" {
-
-		confPath = GetConfigFilePath()
-		s, err := ioutil.ReadFile(confPath)
+		fc, err := loadConfigFile(GetConfigFilePath())
 		if err != nil {
 			return err
 		}
-		err = json.Unmarshal(s, &c)
-		if err != nil {
-			return err
-		}
-		// Retrieves sensible info from the keyring if one is present
-		ConfigFromKeyring(&c)
-
-		// Refresh token if required
-		if refreshed, err := RefreshIfRequired(&c); refreshed {
-			if err != nil {
-				log.Fatal("Could not refresh authentication token:", err)
-			}
-			// Copy config as IdToken will be cleared
-			storeConfig := c
-			ConfigToKeyring(&storeConfig)
-			// Save config to renew TokenExpireAt
-			confData, _ := json.Marshal(&storeConfig)
-			ioutil.WriteFile(confPath, confData, 0666)
-		}
+		c = *fc
 	}
 
 	// Store the retrieved parameters in a public static singleton
@@ -158,6 +197,36 @@ func SetUpEnvironment(confPath string) error {
 	return nil
 }
 
+// loadConfigFile reads a config file, retrieves its sensible info from the keyring and refreshes its token if required.
+func loadConfigFile(confPath string) (*CecConfig, error) {
+	c := &CecConfig{}
+	s, err := ioutil.ReadFile(confPath)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(s, c)
+	if err != nil {
+		return nil, err
+	}
+	c.filePath = confPath
+	// Retrieves sensible info from the keyring if one is present
+	ConfigFromKeyring(c)
+
+	// Refresh token if required
+	if refreshed, err := RefreshIfRequired(c); refreshed {
+		if err != nil {
+			log.Fatal("Could not refresh authentication token:", err)
+		}
+		// Copy config as IdToken will be cleared
+		storeConfig := *c
+		ConfigToKeyring(&storeConfig)
+		// Save config to renew TokenExpireAt
+		confData, _ := json.Marshal(&storeConfig)
+		ioutil.WriteFile(confPath, confData, 0666)
+	}
+	return c, nil
+}
+
 var refreshMux = &sync.Mutex{}
 
 func RefreshAndStoreIfRequired(c *CecConfig) bool {
@@ -174,7 +243,11 @@ func RefreshAndStoreIfRequired(c *CecConfig) bool {
 		ConfigToKeyring(&storeConfig)
 		// Save config to renew TokenExpireAt
 		confData, _ := json.Marshal(&storeConfig)
-		ioutil.WriteFile(GetConfigFilePath(), confData, 0600)
+		confPath := GetConfigFilePath()
+		if c.filePath != "" {
+			confPath = c.filePath
+		}
+		ioutil.WriteFile(confPath, confData, 0600)
 	}
 
 	return refreshed
@@ -0,0 +1,122 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConflictPolicy defines what to do when a transferred file already exists at target path.
+type ConflictPolicy string
+
+const (
+	// ConflictOverwrite replaces the existing file.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictSkip keeps the existing file and does not transfer the source.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictRename transfers the source next to the existing file, under a new name like "name-1.ext".
+	ConflictRename ConflictPolicy = "rename"
+	// ConflictNewer only replaces the existing file if the source has been modified more recently.
+	ConflictNewer ConflictPolicy = "newer"
+	// ConflictFail reports an error for this file.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+var (
+	// OnConflict is the policy applied when a file already exists at target path.
+	OnConflict = ConflictOverwrite
+
+	// renamed keeps track of the names that have been generated during this run,
+	// so that two files are never renamed to the same target.
+	renamed    = make(map[string]struct{})
+	renamedMux sync.Mutex
+)
+
+// ParseConflictPolicy validates the policy passed on the command line.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(s)); p {
+	case ConflictOverwrite, ConflictSkip, ConflictRename, ConflictNewer, ConflictFail:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %s, use one of skip, overwrite, rename, newer or fail", s)
+}
+
+// targetPath computes the path where the passed source file is copied inside this target node.
+func (c *CrawlNode) targetPath(src *CrawlNode) string {
+	bname := src.RelPath
+	if c.NewFileName != "" {
+		bname = c.NewFileName
+	}
+	return c.Join(c.FullPath, bname)
+}
+
+// resolveConflict applies the OnConflict policy to the passed target path. It returns the path
+// where the source must effectively be written, or an empty string if the file must be skipped.
+func (c *CrawlNode) resolveConflict(ctx context.Context, src *CrawlNode, target string) (string, error) {
+	if OnConflict == ConflictOverwrite {
+		return target, nil
+	}
+	exists, mTime := c.statTarget(ctx, target)
+	if !exists {
+		return target, nil
+	}
+	switch OnConflict {
+	case ConflictSkip:
+		return "", nil
+	case ConflictNewer:
+		if src.MTime.After(mTime) {
+			return target, nil
+		}
+		return "", nil
+	case ConflictFail:
+		return "", fmt.Errorf("%s already exists at target path", target)
+	case ConflictRename:
+		return c.uniquePath(ctx, target), nil
+	}
+	return target, nil
+}
+
+// statTarget checks if a file exists at the passed path on this side of the transfer.
+func (c *CrawlNode) statTarget(ctx context.Context, p string) (bool, time.Time) {
+	if c.IsLocal {
+		i, e := os.Stat(p)
+		if e != nil {
+			return false, time.Time{}
+		}
+		return true, i.ModTime()
+	}
+	n, ok := StatNode(ctx, p)
+	if !ok {
+		return false, time.Time{}
+	}
+	return true, NewRemoteNode(n).MTime
+}
+
+// uniquePath finds the first free name in the form "name-1.ext", "name-2.ext", etc.
+// as it is done by the Cells web interface.
+func (c *CrawlNode) uniquePath(ctx context.Context, p string) string {
+	renamedMux.Lock()
+	defer renamedMux.Unlock()
+	dir, base := path.Split(p)
+	if c.IsLocal {
+		dir, base = filepath.Split(p)
+	}
+	ext := path.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	for i := 1; ; i++ {
+		candidate := c.Join(dir, fmt.Sprintf("%s-%d%s", name, i, ext))
+		if _, ok := renamed[candidate]; ok {
+			continue
+		}
+		if exists, _ := c.statTarget(ctx, candidate); exists {
+			continue
+		}
+		renamed[candidate] = struct{}{}
+		return candidate
+	}
+}
@@ -0,0 +1,151 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// downloadParts retrieves a remote file with parallel ranged requests, each part being written at its offset
+// in the local file. Parts that are done are recorded in a journal, so that an interrupted download only
+// retrieves the missing parts on next run. When no journal is found, the first offset bytes of the file
+// are considered valid, as they have been written by a sequential download. When the journal does not match
+// the remote file or the local file anymore, the download starts again from scratch.
+func downloadParts(ctx context.Context, src *CrawlNode, writer *os.File, offset int64, progress func(int64)) error {
+	url := configFrom(ctx).Url
+	journal := loadDownloadJournal(url, writer.Name())
+	if journal != nil {
+		// Parts have been written out of order: nothing can be trusted if the journal is not valid
+		offset = 0
+		if info, e := writer.Stat(); e != nil || !journal.matches(src, info) {
+			journal.remove()
+			journal = nil
+		}
+	}
+	partSize := PartSize
+	if journal != nil {
+		partSize = journal.PartSize
+	} else {
+		// Drop stale content that is not part of the valid prefix
+		if e := writer.Truncate(offset); e != nil {
+			return e
+		}
+		journal = newDownloadJournal(url, writer.Name(), src, partSize)
+		for number := int64(1); number*partSize <= offset; number++ {
+			journal.Parts[number] = true
+		}
+		if e := journal.saveWith(writer); e != nil {
+			return e
+		}
+	}
+
+	// Workers update the journal concurrently
+	done := make(map[int64]bool, len(journal.Parts))
+	for number := range journal.Parts {
+		done[number] = true
+	}
+	numParts := (src.Size + partSize - 1) / partSize
+	queue := make(chan int64)
+	wg := &sync.WaitGroup{}
+	errMux := &sync.Mutex{}
+	var firstErr error
+	setErr := func(e error) {
+		errMux.Lock()
+		if firstErr == nil {
+			firstErr = e
+		}
+		errMux.Unlock()
+	}
+	for w := 0; w < PartsConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range queue {
+				start := (number - 1) * partSize
+				length := partSize
+				if start+length > src.Size {
+					length = src.Size - start
+				}
+				e := downloadPart(ctx, src.FullPath, writer, start, length, progress)
+				if e == nil {
+					e = journal.partDone(number, writer)
+				}
+				if e != nil {
+					setErr(e)
+				}
+			}
+		}()
+	}
+	for number := int64(1); number <= numParts; number++ {
+		if done[number] {
+			length := partSize
+			if number == numParts {
+				length = src.Size - (number-1)*partSize
+			}
+			progress(length)
+			continue
+		}
+		errMux.Lock()
+		failed := firstErr != nil
+		errMux.Unlock()
+		if failed || ctx.Err() != nil {
+			break
+		}
+		queue <- number
+	}
+	close(queue)
+	wg.Wait()
+	if firstErr != nil || ctx.Err() != nil {
+		// Workers are stopped: record the final state of the file so that the download can be resumed
+		_ = journal.saveWith(writer)
+		if firstErr != nil {
+			return firstErr
+		}
+		return ctx.Err()
+	}
+	journal.remove()
+	return nil
+}
+
+// downloadPart copies a range of the remote file at the same offset in the local file.
+// The part is requested again if the connection breaks while its content is read.
+func downloadPart(ctx context.Context, pathToFile string, writer io.WriterAt, start, length int64, progress func(int64)) error {
+	return Retry.Do(ctx, func() error {
+		return readPart(ctx, pathToFile, writer, start, length, progress)
+	})
+}
+
+func readPart(ctx context.Context, pathToFile string, writer io.WriterAt, start, length int64, progress func(int64)) error {
+	body, e := GetFileRange(ctx, pathToFile, start, length)
+	if e != nil {
+		// Request has already been retried by the client
+		return permanent(e)
+	}
+	defer body.Close()
+	var written int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, e := writer.WriteAt(buf[:n], start+written); e != nil {
+				progress(-written)
+				return permanent(e)
+			}
+			written += int64(n)
+			progress(int64(n))
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			progress(-written)
+			return err
+		}
+	}
+	if written != length {
+		progress(-written)
+		return fmt.Errorf("received %d bytes for part at offset %d of %s, expected %d", written, start, pathToFile, length)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	cells_sdk "github.com/pydio/cells-sdk-go"
+)
+
+// rangeServer serves the same content for any object and records the requested ranges.
+type rangeServer struct {
+	*httptest.Server
+	mux    sync.Mutex
+	ranges []string
+}
+
+func (s *rangeServer) requested() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := append([]string{}, s.ranges...)
+	sort.Strings(res)
+	s.ranges = nil
+	return res
+}
+
+// newRangeServer starts a fake S3 server. As the bucket is moved to the host name by the S3 client,
+// all connections are redirected to the server while it is running.
+func newRangeServer(t *testing.T, content []byte) (*rangeServer, context.Context) {
+	s := &rangeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mux.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.mux.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	// A custom CA bundle cannot be applied to the transport of the S3 client, and is useless over plain HTTP
+	if bundle, ok := os.LookupEnv("AWS_CA_BUNDLE"); ok {
+		os.Unsetenv("AWS_CA_BUNDLE")
+		t.Cleanup(func() { os.Setenv("AWS_CA_BUNDLE", bundle) })
+	}
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, s.Listener.Addr().String())
+		},
+	}
+	t.Cleanup(func() {
+		http.DefaultTransport = defaultTransport
+		s.Close()
+	})
+	return s, testContext(s.URL)
+}
+
+// testContext returns a context whose config targets the passed server with a valid token.
+func testContext(url string) context.Context {
+	conf := &CecConfig{SdkConfig: cells_sdk.SdkConfig{
+		Url:            url,
+		IdToken:        "token",
+		RefreshToken:   "refresh",
+		TokenExpiresAt: int(time.Now().Add(time.Hour).Unix()),
+	}}
+	return WithConfig(context.Background(), conf)
+}
+
+func TestDownloadPartsResume(t *testing.T) {
+	dir, e := ioutil.TempDir("", "download")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	confPath, partSize, concurrency := GetConfigFilePath(), PartSize, PartsConcurrency
+	SetConfigFilePath(filepath.Join(dir, "config.json"))
+	PartSize, PartsConcurrency = 4, 2
+	defer func() {
+		SetConfigFilePath(confPath)
+		PartSize, PartsConcurrency = partSize, concurrency
+	}()
+
+	content := []byte("0123456789abcd")
+	server, ctx := newRangeServer(t, content)
+	src := &CrawlNode{FullPath: "personal-files/file.bin", Size: int64(len(content)), MTime: time.Unix(1600000000, 0)}
+	url := configFrom(ctx).Url
+	partLocation := filepath.Join(dir, "file.bin"+PartFileSuffix)
+	allParts := []string{"bytes=0-3", "bytes=12-13", "bytes=4-7", "bytes=8-11"}
+
+	tests := []struct {
+		name string
+		// prepare writes the '.part' file left by a previous run and returns the offset of the valid prefix
+		prepare  func(f *os.File) int64
+		expected []string
+	}{
+		{"no journal", func(f *os.File) int64 {
+			return 0
+		}, allParts},
+		{"sequential prefix", func(f *os.File) int64 {
+			f.Write(content[:9])
+			return 9
+		}, []string{"bytes=12-13", "bytes=8-11"}},
+		{"partial journal", func(f *os.File) int64 {
+			f.WriteAt(content[4:8], 4)
+			f.WriteAt([]byte("xx"), 12)
+			j := newDownloadJournal(url, f.Name(), src, PartSize)
+			j.partDone(2, f)
+			return 6
+		}, []string{"bytes=0-3", "bytes=12-13", "bytes=8-11"}},
+		{"part file modified after the journal", func(f *os.File) int64 {
+			f.WriteAt(content[4:8], 4)
+			j := newDownloadJournal(url, f.Name(), src, PartSize)
+			j.partDone(2, f)
+			f.WriteAt([]byte("corrupted"), 0)
+			return 9
+		}, allParts},
+		{"part file replaced", func(f *os.File) int64 {
+			f.WriteAt(content[4:8], 4)
+			j := newDownloadJournal(url, f.Name(), src, PartSize)
+			j.partDone(2, f)
+			f.Truncate(0)
+			return 0
+		}, allParts},
+		{"journal of another version", func(f *os.File) int64 {
+			f.WriteAt(content[:4], 0)
+			other := *src
+			other.MTime = src.MTime.Add(-time.Hour)
+			j := newDownloadJournal(url, f.Name(), &other, PartSize)
+			j.partDone(1, f)
+			return 4
+		}, allParts},
+	}
+	for _, tt := range tests {
+		_ = os.Remove(partLocation)
+		f, e := os.OpenFile(partLocation, os.O_CREATE|os.O_RDWR, 0644)
+		if e != nil {
+			t.Fatal(e)
+		}
+		offset := tt.prepare(f)
+		server.requested()
+		if e := downloadParts(ctx, src, f, offset, func(int64) {}); e != nil {
+			t.Fatalf("%s: %s", tt.name, e.Error())
+		}
+		f.Close()
+		if got := server.requested(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected ranges %v, got %v", tt.name, tt.expected, got)
+		}
+		if data, _ := ioutil.ReadFile(partLocation); !bytes.Equal(data, content) {
+			t.Errorf("%s: downloaded %q, expected %q", tt.name, data, content)
+		}
+		if loadDownloadJournal(url, partLocation) != nil {
+			t.Errorf("%s: journal should be removed once the download is complete", tt.name)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package rest
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Client side encryption uses envelope encryption: the content of each file is encrypted with its own random
+// data key, that is itself encrypted with the key of the user and stored in the metadata of the object.
+// Content is split in chunks that are sealed separately with AES-GCM, so that files of any size are streamed.
+// The nonce of each chunk is its index, plus a flag for the last chunk, so that chunks cannot be reordered,
+// dropped or truncated without the decryption failing.
+const (
+	// encryptionScheme is the value of the marker metadata of encrypted objects.
+	encryptionScheme = "aes-256-gcm-chunked-v1"
+	// encryptionChunkSize is the size of the plain chunks, each of them grows by the size of the GCM tag.
+	encryptionChunkSize = 64 * 1024
+	encryptionTagSize   = 16
+	// pbkdf2Iterations is the cost of the derivation of a key from a passphrase.
+	pbkdf2Iterations = 200000
+
+	encryptionMeta     = "Cec-Encryption"
+	encryptionKeyMeta  = "Cec-Encryption-Key"
+	encryptionSaltMeta = "Cec-Encryption-Salt"
+	encryptionSizeMeta = "Cec-Encryption-Size"
+
+	// encryptedContentType is the MIME type of encrypted objects, whose content cannot be previewed.
+	encryptedContentType = "application/octet-stream"
+
+	keyringEncryptionKey = "EncryptionKey"
+	// EncryptionPassphraseEnv is the environment variable that can hold the encryption passphrase.
+	EncryptionPassphraseEnv = "CELLS_CLIENT_ENCRYPTION_PASSPHRASE"
+)
+
+var (
+	// Encrypt makes uploads encrypt the content of the files on the client side.
+	Encrypt bool
+	// Encryption is the key used to encrypt uploads and decrypt downloads. If it is nil, the key stored
+	// in the keyring, if any, is used.
+	Encryption *EncryptionKey
+
+	keyringKeyOnce sync.Once
+	keyringKey     *EncryptionKey
+)
+
+// EncryptionKey is the key of the user, derived from a passphrase or read from a key file.
+type EncryptionKey struct {
+	passphrase []byte
+	key        []byte
+
+	// Keys derived from the passphrase, by salt, as the derivation is deliberately slow
+	derived map[string][]byte
+	// salt is used for all the uploads of the run
+	salt []byte
+	mux  sync.Mutex
+}
+
+// NewPassphraseKey creates a key that is derived from the passphrase with PBKDF2, using a random salt for uploads.
+func NewPassphraseKey(passphrase string) (*EncryptionKey, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("encryption passphrase cannot be empty")
+	}
+	return &EncryptionKey{passphrase: []byte(passphrase), derived: map[string][]byte{}}, nil
+}
+
+// NewFileKey creates a key from the content of a key file: 32 bytes are used as is, other contents are hashed.
+func NewFileKey(data []byte) (*EncryptionKey, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("encryption key file is empty")
+	}
+	if len(data) == 32 {
+		return &EncryptionKey{key: data}, nil
+	}
+	sum := sha256.Sum256(data)
+	return &EncryptionKey{key: sum[:]}, nil
+}
+
+// GenerateEncryptionKey returns 32 random bytes, suitable for a key file.
+func GenerateEncryptionKey() ([]byte, error) {
+	data := make([]byte, 32)
+	_, e := io.ReadFull(rand.Reader, data)
+	return data, e
+}
+
+// StoreEncryptionKey stores the content of a key file in the keyring, to be used when no key is passed.
+func StoreEncryptionKey(data []byte) error {
+	if _, e := NewFileKey(data); e != nil {
+		return e
+	}
+	return keyring.Set(keyringService, keyringEncryptionKey, base64.StdEncoding.EncodeToString(data))
+}
+
+// ClearEncryptionKey removes the key stored in the keyring, if any.
+func ClearEncryptionKey() error {
+	if e := keyring.Delete(keyringService, keyringEncryptionKey); e != nil && e != keyring.ErrNotFound {
+		return e
+	}
+	return nil
+}
+
+// encryptionKey returns the key passed by the user, or the one stored in the keyring. It is nil if there is none.
+func encryptionKey() *EncryptionKey {
+	if Encryption != nil {
+		return Encryption
+	}
+	keyringKeyOnce.Do(func() {
+		value, e := keyring.Get(keyringService, keyringEncryptionKey)
+		if e != nil {
+			return
+		}
+		if data, e := base64.StdEncoding.DecodeString(value); e == nil {
+			keyringKey, _ = NewFileKey(data)
+		}
+	})
+	return keyringKey
+}
+
+// CheckEncryptionKey verifies that a key is available when uploads must be encrypted.
+func CheckEncryptionKey() error {
+	if Encrypt && encryptionKey() == nil {
+		return fmt.Errorf("no encryption key: pass a passphrase or a key file, or store a key with 'configure encryption'")
+	}
+	return nil
+}
+
+// keyEncryptionKey returns the key that encrypts the data keys, derived with the passed salt for passphrases.
+func (k *EncryptionKey) keyEncryptionKey(salt []byte) ([]byte, error) {
+	if k.passphrase == nil {
+		if len(salt) > 0 {
+			return nil, fmt.Errorf("file has been encrypted with a passphrase, not with a key file")
+		}
+		return k.key, nil
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("file has been encrypted with a key file, not with a passphrase")
+	}
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if d, ok := k.derived[string(salt)]; ok {
+		return d, nil
+	}
+	d := pbkdf2.Key(k.passphrase, salt, pbkdf2Iterations, 32, sha256.New)
+	k.derived[string(salt)] = d
+	return d, nil
+}
+
+// uploadSalt returns the salt used to derive the passphrase for the uploads of this run, or nil for key files.
+func (k *EncryptionKey) uploadSalt() ([]byte, error) {
+	if k.passphrase == nil {
+		return nil, nil
+	}
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if k.salt == nil {
+		salt := make([]byte, 16)
+		if _, e := io.ReadFull(rand.Reader, salt); e != nil {
+			return nil, e
+		}
+		k.salt = salt
+	}
+	return k.salt, nil
+}
+
+// encrypt returns a reader that encrypts the content with a new data key, and the metadata to store with the
+// object, in addition to the passed ones. Size is the size of the plain content, or -1 if it is unknown.
+func (k *EncryptionKey) encrypt(content io.Reader, meta map[string]*string, size int64) (io.Reader, map[string]*string, error) {
+	salt, e := k.uploadSalt()
+	if e != nil {
+		return nil, nil, e
+	}
+	kek, e := k.keyEncryptionKey(salt)
+	if e != nil {
+		return nil, nil, e
+	}
+	dataKey := make([]byte, 32)
+	if _, e := io.ReadFull(rand.Reader, dataKey); e != nil {
+		return nil, nil, e
+	}
+	wrapped, e := seal(kek, dataKey)
+	if e != nil {
+		return nil, nil, e
+	}
+	aead, e := newGCM(dataKey)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	m := make(map[string]*string, len(meta)+4)
+	for key, v := range meta {
+		m[key] = v
+	}
+	m[encryptionMeta] = aws.String(encryptionScheme)
+	m[encryptionKeyMeta] = aws.String(base64.StdEncoding.EncodeToString(wrapped))
+	if salt != nil {
+		m[encryptionSaltMeta] = aws.String(base64.StdEncoding.EncodeToString(salt))
+	}
+	if size >= 0 {
+		m[encryptionSizeMeta] = aws.String(strconv.FormatInt(size, 10))
+	}
+	return &encryptReader{aead: aead, src: content}, m, nil
+}
+
+// decrypt returns a reader of the plain content of an encrypted object, and the size of this content.
+func (k *EncryptionKey) decrypt(content io.Reader, meta map[string]*string, encryptedSize int64) (io.Reader, int64, error) {
+	if scheme := metadata(meta, encryptionMeta); scheme != encryptionScheme {
+		return nil, 0, fmt.Errorf("unsupported encryption scheme %s", scheme)
+	}
+	var salt []byte
+	if s := metadata(meta, encryptionSaltMeta); s != "" {
+		var e error
+		if salt, e = base64.StdEncoding.DecodeString(s); e != nil {
+			return nil, 0, fmt.Errorf("invalid encryption salt: %s", e.Error())
+		}
+	}
+	kek, e := k.keyEncryptionKey(salt)
+	if e != nil {
+		return nil, 0, e
+	}
+	wrapped, e := base64.StdEncoding.DecodeString(metadata(meta, encryptionKeyMeta))
+	if e != nil {
+		return nil, 0, fmt.Errorf("invalid encrypted data key: %s", e.Error())
+	}
+	dataKey, e := open(kek, wrapped)
+	if e != nil {
+		return nil, 0, fmt.Errorf("cannot decrypt the data key, the encryption key is probably wrong")
+	}
+	aead, e := newGCM(dataKey)
+	if e != nil {
+		return nil, 0, e
+	}
+	return &decryptReader{aead: aead, src: content}, plainObjectSize(meta, encryptedSize), nil
+}
+
+// decryptObject decrypts the content of a remote object if it has been encrypted on upload, in which case
+// it fails if no key is available. Otherwise, the content is returned as is.
+func decryptObject(pathToFile string, content io.Reader, meta map[string]*string, size int64) (io.Reader, int64, error) {
+	if !isEncrypted(meta) {
+		return content, size, nil
+	}
+	key := encryptionKey()
+	if key == nil {
+		return nil, 0, fmt.Errorf("%s is encrypted, pass the passphrase or the key file that has been used to encrypt it", pathToFile)
+	}
+	plain, size, e := key.decrypt(content, meta, size)
+	if e != nil {
+		return nil, 0, fmt.Errorf("cannot decrypt %s: %s", pathToFile, e.Error())
+	}
+	return plain, size, nil
+}
+
+// OpenFile retrieves the content of a remote file, that is transparently decrypted if it has been encrypted
+// on upload. Returned size is the size of the plain content.
+func OpenFile(ctx context.Context, pathToFile string) (io.ReadCloser, int64, error) {
+	h, body, e := getObject(ctx, pathToFile, 0)
+	if e != nil {
+		return nil, 0, e
+	}
+	plain, size, e := decryptObject(pathToFile, body, h.Metadata, *h.ContentLength)
+	if e != nil {
+		body.Close()
+		return nil, 0, e
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{plain, body}, size, nil
+}
+
+// contentSize returns the size of the content of a file, that is the size of the plain content for remote files
+// that have been encrypted on upload. It retrieves the metadata of remote files: compare the sizes of the nodes first.
+func (c *CrawlNode) contentSize(ctx context.Context) int64 {
+	if c.IsLocal || c.IsDir {
+		return c.Size
+	}
+	if h, e := c.objectInfo(ctx); e == nil && isEncrypted(h.Metadata) {
+		return plainObjectSize(h.Metadata, c.Size)
+	}
+	return c.Size
+}
+
+// isEncrypted checks the marker metadata of an object.
+func isEncrypted(meta map[string]*string) bool {
+	return metadata(meta, encryptionMeta) != ""
+}
+
+// encryptionMetadata returns the metadata that must be copied along with the content of an encrypted
+// object, so that it can still be decrypted. It returns nil if the object is not encrypted.
+func encryptionMetadata(meta map[string]*string) map[string]*string {
+	if !isEncrypted(meta) {
+		return nil
+	}
+	m := make(map[string]*string)
+	for _, k := range []string{encryptionMeta, encryptionKeyMeta, encryptionSaltMeta, encryptionSizeMeta} {
+		if v := metadata(meta, k); v != "" {
+			m[k] = aws.String(v)
+		}
+	}
+	return m
+}
+
+// metadata finds a value in the user metadata of an object, whose keys may have been normalized by the server.
+func metadata(meta map[string]*string, key string) string {
+	for k, v := range meta {
+		if strings.EqualFold(k, key) && v != nil {
+			return *v
+		}
+	}
+	return ""
+}
+
+// encryptedSize computes the size of the encrypted content for a plain content of the passed size.
+func encryptedSize(size int64) int64 {
+	chunks := (size + encryptionChunkSize - 1) / encryptionChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*encryptionTagSize
+}
+
+// plainObjectSize returns the size of the plain content of an encrypted object, as stored in its metadata.
+func plainObjectSize(meta map[string]*string, encryptedSize int64) int64 {
+	if s, e := strconv.ParseInt(metadata(meta, encryptionSizeMeta), 10, 64); e == nil {
+		return s
+	}
+	return plainSize(encryptedSize)
+}
+
+// plainSize computes the size of the plain content for an encrypted content of the passed size.
+func plainSize(size int64) int64 {
+	sealed := int64(encryptionChunkSize + encryptionTagSize)
+	chunks := (size + sealed - 1) / sealed
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size - chunks*encryptionTagSize
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts a data key with a random nonce, that is prepended to the result.
+func seal(key, data []byte) ([]byte, error) {
+	aead, e := newGCM(key)
+	if e != nil {
+		return nil, e
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, e := io.ReadFull(rand.Reader, nonce); e != nil {
+		return nil, e
+	}
+	return aead.Seal(nonce, nonce, data, []byte(encryptionScheme)), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	aead, e := newGCM(key)
+	if e != nil {
+		return nil, e
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed data is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(encryptionScheme))
+}
+
+// chunkNonce computes the nonce of the chunk at the given index, the last byte flagging the last chunk.
+func chunkNonce(index uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], index)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// readChunk reads up to size bytes, a short chunk meaning that the end of the content has been reached.
+func readChunk(r io.Reader, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	n, e := io.ReadFull(r, buf)
+	if e == io.EOF || e == io.ErrUnexpectedEOF {
+		e = nil
+	}
+	return buf[:n], e
+}
+
+// encryptReader seals the content chunk by chunk. One chunk is read ahead to know which one is the last.
+type encryptReader struct {
+	aead    cipher.AEAD
+	src     io.Reader
+	index   uint64
+	next    []byte
+	started bool
+	pending []byte
+	done    bool
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if e := r.sealNext(); e != nil {
+			return 0, e
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *encryptReader) sealNext() error {
+	if !r.started {
+		chunk, e := readChunk(r.src, encryptionChunkSize)
+		if e != nil {
+			return e
+		}
+		r.next, r.started = chunk, true
+	}
+	current := r.next
+	var e error
+	if len(current) == encryptionChunkSize {
+		if r.next, e = readChunk(r.src, encryptionChunkSize); e != nil {
+			return e
+		}
+	} else {
+		r.next = nil
+	}
+	last := len(r.next) == 0
+	r.pending = r.aead.Seal(nil, chunkNonce(r.index, last), current, nil)
+	r.index++
+	r.done = last
+	return nil
+}
+
+// decryptReader opens the content chunk by chunk, and fails if it has been truncated or altered.
+type decryptReader struct {
+	aead    cipher.AEAD
+	src     io.Reader
+	index   uint64
+	next    []byte
+	started bool
+	pending []byte
+	done    bool
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if e := r.openNext(); e != nil {
+			return 0, e
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *decryptReader) openNext() error {
+	const sealedSize = encryptionChunkSize + encryptionTagSize
+	if !r.started {
+		chunk, e := readChunk(r.src, sealedSize)
+		if e != nil {
+			return e
+		}
+		r.next, r.started = chunk, true
+	}
+	current := r.next
+	var e error
+	if len(current) == sealedSize {
+		if r.next, e = readChunk(r.src, sealedSize); e != nil {
+			return e
+		}
+	} else {
+		r.next = nil
+	}
+	last := len(r.next) == 0
+	plain, e := r.aead.Open(nil, chunkNonce(r.index, last), current, nil)
+	if e != nil {
+		return fmt.Errorf("encrypted content is corrupted or truncated")
+	}
+	r.pending = plain
+	r.index++
+	r.done = last
+	return nil
+}
@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"testing"
+)
+
+// encryptBytes encrypts plain with the key and returns the encrypted content and its metadata.
+func encryptBytes(t *testing.T, k *EncryptionKey, plain []byte, size int64) ([]byte, map[string]*string) {
+	r, meta, e := k.encrypt(bytes.NewReader(plain), nil, size)
+	if e != nil {
+		t.Fatal(e)
+	}
+	data, e := ioutil.ReadAll(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return data, meta
+}
+
+// decryptBytes decrypts the whole content with the key.
+func decryptBytes(k *EncryptionKey, data []byte, meta map[string]*string) ([]byte, int64, error) {
+	r, size, e := k.decrypt(bytes.NewReader(data), meta, int64(len(data)))
+	if e != nil {
+		return nil, 0, e
+	}
+	plain, e := ioutil.ReadAll(r)
+	return plain, size, e
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	passphrase, e := NewPassphraseKey("correct horse battery staple")
+	if e != nil {
+		t.Fatal(e)
+	}
+	keyFile, e := NewFileKey([]byte("content of a key file"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	sizes := []int{0, 1, encryptionChunkSize - 1, encryptionChunkSize, encryptionChunkSize + 1,
+		2 * encryptionChunkSize, 3*encryptionChunkSize + 5}
+	for name, k := range map[string]*EncryptionKey{"passphrase": passphrase, "key file": keyFile} {
+		for _, size := range sizes {
+			plain := make([]byte, size)
+			if _, e := rand.Read(plain); e != nil {
+				t.Fatal(e)
+			}
+			data, meta := encryptBytes(t, k, plain, int64(size))
+			if int64(len(data)) != encryptedSize(int64(size)) {
+				t.Errorf("%s, %d bytes: encrypted to %d bytes, expected %d", name, size, len(data), encryptedSize(int64(size)))
+			}
+			if plainSize(int64(len(data))) != int64(size) {
+				t.Errorf("%s, %d bytes: plainSize returns %d", name, size, plainSize(int64(len(data))))
+			}
+			got, gotSize, e := decryptBytes(k, data, meta)
+			if e != nil {
+				t.Errorf("%s, %d bytes: %s", name, size, e.Error())
+				continue
+			}
+			if gotSize != int64(size) || !bytes.Equal(got, plain) {
+				t.Errorf("%s, %d bytes: decrypted content differs", name, size)
+			}
+		}
+	}
+}
+
+func TestEncryptionUnknownSize(t *testing.T) {
+	k, _ := NewFileKey([]byte("key"))
+	plain := bytes.Repeat([]byte("a"), encryptionChunkSize+10)
+	data, meta := encryptBytes(t, k, plain, -1)
+	if metadata(meta, encryptionSizeMeta) != "" {
+		t.Error("size must not be stored when it is unknown")
+	}
+	got, size, e := decryptBytes(k, data, meta)
+	if e != nil || size != int64(len(plain)) || !bytes.Equal(got, plain) {
+		t.Errorf("cannot decrypt content of unknown size: %v", e)
+	}
+}
+
+func TestDecryptTruncated(t *testing.T) {
+	k, _ := NewFileKey([]byte("key"))
+	plain := make([]byte, 3*encryptionChunkSize)
+	data, meta := encryptBytes(t, k, plain, int64(len(plain)))
+	sealed := encryptionChunkSize + encryptionTagSize
+	for _, length := range []int{0, 10, sealed - 1, sealed, sealed + 1, 2 * sealed, len(data) - 1} {
+		if _, _, e := decryptBytes(k, data[:length], meta); e == nil {
+			t.Errorf("content truncated to %d bytes has been decrypted", length)
+		}
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	k, _ := NewFileKey([]byte("key"))
+	plain := make([]byte, 2*encryptionChunkSize)
+	data, meta := encryptBytes(t, k, plain, int64(len(plain)))
+	data[encryptionChunkSize+encryptionTagSize+3] ^= 1
+	if _, _, e := decryptBytes(k, data, meta); e == nil {
+		t.Error("altered content has been decrypted")
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	k, _ := NewPassphraseKey("passphrase")
+	data, meta := encryptBytes(t, k, []byte("secret"), 6)
+
+	other, _ := NewPassphraseKey("other passphrase")
+	if _, _, e := decryptBytes(other, data, meta); e == nil {
+		t.Error("content has been decrypted with a wrong passphrase")
+	}
+	keyFile, _ := NewFileKey([]byte("passphrase"))
+	if _, _, e := decryptBytes(keyFile, data, meta); e == nil {
+		t.Error("content encrypted with a passphrase has been decrypted with a key file")
+	}
+}
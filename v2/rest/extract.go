@@ -0,0 +1,232 @@
+package rest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uiprogress"
+)
+
+// archiveEntry is a folder or a regular file read from a local archive.
+type archiveEntry struct {
+	name  string
+	isDir bool
+	size  int64
+	mTime time.Time
+	// open returns the content of a file entry, it must be consumed before reading the next entry.
+	open func() (io.ReadCloser, error)
+}
+
+// ExtractArchive uploads the content of a local zip, tar or tar.gz archive under the remote target folder,
+// each entry becoming a separate node. Entries are read and uploaded one after the other, so that the
+// archive is never extracted on the local disk. Missing folders are created before their first child.
+func ExtractArchive(ctx context.Context, archivePath, targetPath string, filter *Filter, pool *BarsPool) (errs []error) {
+	c := &CrawlNode{IsDir: true, FullPath: targetPath}
+	if e := c.createRoot(ctx); e != nil {
+		pool.Stop()
+		return []error{e}
+	}
+
+	created := make(map[string]bool)
+	// mkdirAll creates the passed folder and its missing ancestors, relative to the target root
+	mkdirAll := func(dir string) error {
+		var folders []*CrawlNode
+		for d := dir; d != "." && d != "/" && !created[d]; d = path.Dir(d) {
+			created[d] = true
+			folder := &CrawlNode{IsDir: true, RelPath: d}
+			pool.discovered(folder)
+			folders = append([]*CrawlNode{folder}, folders...)
+		}
+		if len(folders) == 0 {
+			return nil
+		}
+		return c.createFolders(ctx, folders, pool)
+	}
+
+	idx := -1
+	pool.startDiscovery()
+	e := readArchive(archivePath, func(entry *archiveEntry) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if excludedEntry(filter, entry.name, entry.isDir) {
+			return nil
+		}
+		if entry.isDir {
+			return mkdirAll(entry.name)
+		}
+		if e := mkdirAll(path.Dir(entry.name)); e != nil {
+			return e
+		}
+
+		src := &CrawlNode{RelPath: entry.name, FullPath: path.Join(archivePath, entry.name), MTime: entry.mTime, Size: entry.size}
+		pool.discovered(src)
+		defer pool.Done()
+		idx++
+		barSize := entry.size
+		if barSize == 0 {
+			barSize = 1
+		}
+		bar := pool.Get(idx, int(barSize), path.Base(entry.name))
+		defer pool.fileDone(bar)
+		report := newFileReport(src, c.targetPath(src))
+		dest, e := c.resolveConflict(ctx, src, report.Target)
+		if e == nil && dest == "" {
+			appendToBar(bar, "(skipped, already exists)")
+			bar.Set(bar.Total)
+			pool.Summary.skipped(report)
+			return nil
+		}
+		if e == nil {
+			report.Target = dest
+			e = c.extractFile(ctx, entry, dest, bar)
+			if e == nil {
+				e = UserMeta.apply(ctx, entry.name, dest)
+			}
+		}
+		if e != nil {
+			errs = append(errs, e)
+			pool.Summary.failed(report, e, ctx.Err() != nil)
+			// Go on with the next entries, unless the transfer has been cancelled
+			return ctx.Err()
+		}
+		bar.Set(bar.Total)
+		pool.Summary.transferred(report)
+		return nil
+	})
+	pool.stopDiscovery()
+	pool.Stop()
+	if e != nil && e != ctx.Err() {
+		errs = append(errs, e)
+	}
+	return
+}
+
+// extractFile uploads an entry of the archive and verifies it if required. As the content of the archive
+// is read sequentially, an entry cannot be sent again in case of checksum mismatch.
+func (c *CrawlNode) extractFile(ctx context.Context, entry *archiveEntry, fp string, bar *uiprogress.Bar) error {
+	reader, e := entry.open()
+	if e != nil {
+		return e
+	}
+	defer reader.Close()
+	sums, e := c.sendStream(ctx, reader, entry.size, timeMetadata(entry.mTime), fp, bar)
+	if e != nil || sums == nil {
+		return e
+	}
+	eTag, sha, e := remoteChecksums(ctx, fp)
+	if e == nil {
+		e = sums.verify(eTag, sha)
+	}
+	if errors.Is(e, errCannotVerify) {
+		appendToBar(bar, "(unverified)")
+		return nil
+	} else if e != nil {
+		return fmt.Errorf("%s: %w", fp, e)
+	}
+	return nil
+}
+
+// readArchive passes the folders and regular files of a zip, tar or tar.gz archive to the callback, in the order
+// in which they are stored. The format is detected from the content of the file. Other entries, such as
+// symbolic links, are ignored.
+func readArchive(archivePath string, callback func(entry *archiveEntry) error) error {
+	f, e := os.Open(archivePath)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	if bytes.HasPrefix(magic, []byte("PK\x03\x04")) {
+		return readZip(archivePath, callback)
+	}
+
+	var r io.Reader = br
+	if bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) {
+		gz, e := gzip.NewReader(br)
+		if e != nil {
+			return e
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, e := tr.Next()
+		if e == io.EOF {
+			return nil
+		} else if e != nil {
+			return fmt.Errorf("cannot read archive %s: %s", archivePath, e.Error())
+		}
+		name, ok := cleanEntryName(h.Name)
+		if !ok {
+			continue
+		}
+		entry := &archiveEntry{name: name, size: h.Size, mTime: h.ModTime}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			entry.isDir = true
+		case tar.TypeReg, tar.TypeRegA:
+			entry.open = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(tr), nil
+			}
+		default:
+			continue
+		}
+		if e := callback(entry); e != nil {
+			return e
+		}
+	}
+}
+
+func readZip(archivePath string, callback func(entry *archiveEntry) error) error {
+	zr, e := zip.OpenReader(archivePath)
+	if e != nil {
+		return e
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		name, ok := cleanEntryName(f.Name)
+		if !ok {
+			continue
+		}
+		mode := f.Mode()
+		if !mode.IsDir() && !mode.IsRegular() {
+			continue
+		}
+		entry := &archiveEntry{name: name, isDir: mode.IsDir(), size: int64(f.UncompressedSize64), mTime: f.Modified, open: f.Open}
+		if e := callback(entry); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// excludedEntry checks the entry and its ancestors against the filter, as they are not walked like folders.
+func excludedEntry(filter *Filter, name string, isDir bool) bool {
+	for d := path.Dir(name); d != "."; d = path.Dir(d) {
+		if filter.Excluded(d, true) {
+			return true
+		}
+	}
+	return filter.Excluded(name, isDir)
+}
+
+// cleanEntryName makes the name of an entry relative to the target folder, so that it cannot be written outside of it.
+func cleanEntryName(name string) (string, bool) {
+	p := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	return p, p != ""
+}
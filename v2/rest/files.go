@@ -1,16 +1,17 @@
 package rest
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/pydio/cells-sdk-go/client/tree_service"
@@ -21,79 +22,147 @@ import (
 	"github.com/pydio/cells-client/v2/common"
 )
 
+// BulkPageSize is the number of nodes that are retrieved by each call to the BulkStatNodes API.
+const BulkPageSize int32 = 100
+
 func GetS3Client() (*s3.S3, string, error) {
-	DefaultConfig.CustomHeaders = map[string]string{"User-Agent": "cells-client/" + common.Version}
-	if err := ConfigFromKeyring(DefaultConfig); err != nil {
+	return s3ClientFor(DefaultConfig)
+}
+
+// getS3Client returns a S3 client for the server whose config is carried by the context.
+func getS3Client(ctx context.Context) (*s3.S3, string, error) {
+	return s3ClientFor(configFrom(ctx))
+}
+
+func s3ClientFor(conf *CecConfig) (*s3.S3, string, error) {
+	// Config is shared by parallel transfers and updated when its token is refreshed
+	refreshMux.Lock()
+	defer refreshMux.Unlock()
+	conf.CustomHeaders = map[string]string{"User-Agent": "cells-client/" + common.Version}
+	if err := ConfigFromKeyring(conf); err != nil {
 		return nil, "", err
 	}
-	s3Config := getS3ConfigFromSdkConfig(DefaultConfig)
+	s3Config := getS3ConfigFromSdkConfig(conf)
 	bucketName := s3Config.Bucket
-	s3Client, e := awstransport.GetS3CLient(&DefaultConfig.SdkConfig, &s3Config)
+	s3Client, e := awstransport.GetS3CLient(&conf.SdkConfig, &s3Config)
 	if e != nil {
 		return nil, "", e
 	}
 	s3Client.Config.S3DisableContentMD5Validation = aws.Bool(true)
+	s3Client.Retryer = s3Retryer{policy: Retry}
 	return s3Client, bucketName, e
 }
 
-func GetFile(pathToFile string) (io.Reader, int, error) {
+func GetFile(ctx context.Context, pathToFile string) (io.Reader, int, error) {
+	return GetFileFrom(ctx, pathToFile, 0)
+}
 
-	s3Client, bucketName, e := GetS3Client()
+// GetFileFrom retrieves the content of a remote file starting at the given offset, using a S3 Range request
+// when the offset is not zero. Returned length is the number of bytes that remain to be read.
+func GetFileFrom(ctx context.Context, pathToFile string, offset int64) (io.Reader, int, error) {
+	hO, body, e := getObject(ctx, pathToFile, offset)
 	if e != nil {
 		return nil, 0, e
 	}
-	hO, err := s3Client.HeadObject((&s3.HeadObjectInput{}).
+	return body, int(*hO.ContentLength - offset), nil
+}
+
+// getObject retrieves the metadata of a remote file and its content from the given offset.
+func getObject(ctx context.Context, pathToFile string, offset int64) (*s3.HeadObjectOutput, io.ReadCloser, error) {
+	hO, err := HeadFile(ctx, pathToFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	size := *hO.ContentLength
+	if offset > size {
+		return nil, nil, fmt.Errorf("cannot read %s from offset %d, file is only %d bytes long", pathToFile, offset, size)
+	}
+	body, err := readObject(ctx, pathToFile, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hO, body, nil
+}
+
+// readObject retrieves the content of a remote file from the given offset, when its metadata are already known.
+// Returned body must be closed by the caller.
+func readObject(ctx context.Context, pathToFile string, offset int64) (io.ReadCloser, error) {
+	s3Client, bucketName, e := getS3Client(ctx)
+	if e != nil {
+		return nil, e
+	}
+	input := (&s3.GetObjectInput{}).
 		SetBucket(bucketName).
-		SetKey(pathToFile),
+		SetKey(pathToFile)
+	if offset > 0 {
+		input.SetRange(fmt.Sprintf("bytes=%d-", offset))
+	}
+	obj, e := s3Client.GetObjectWithContext(ctx, input, refreshCredentials(configFrom(ctx)), throttleTransfer)
+	if e != nil {
+		return nil, e
+	}
+	return obj.Body, nil
+}
+
+// GetFileRange retrieves length bytes of a remote file starting at the given offset.
+// Returned body must be closed by the caller.
+func GetFileRange(ctx context.Context, pathToFile string, offset, length int64) (io.ReadCloser, error) {
+	s3Client, bucketName, e := getS3Client(ctx)
+	if e != nil {
+		return nil, e
+	}
+	obj, e := s3Client.GetObjectWithContext(ctx, (&s3.GetObjectInput{}).
+		SetBucket(bucketName).
+		SetKey(pathToFile).
+		SetRange(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+		refreshCredentials(configFrom(ctx)),
+		throttleTransfer,
 	)
-	if err != nil {
-		return nil, 0, err
+	if e != nil {
+		return nil, e
 	}
-	size := int(*hO.ContentLength)
+	return obj.Body, nil
+}
 
-	obj, err := s3Client.GetObject((&s3.GetObjectInput{}).
+// HeadFile retrieves the S3 metadata of a remote file, including its size and ETag.
+func HeadFile(ctx context.Context, pathToFile string) (*s3.HeadObjectOutput, error) {
+	s3Client, bucketName, e := getS3Client(ctx)
+	if e != nil {
+		return nil, e
+	}
+	return s3Client.HeadObjectWithContext(ctx, (&s3.HeadObjectInput{}).
 		SetBucket(bucketName).
 		SetKey(pathToFile),
 	)
-	if err != nil {
-		return nil, 0, err
-	}
-	return obj.Body, size, nil
 }
 
-func PutFile(pathToFile string, content io.ReadSeeker, checkExists bool, errChan ...chan error) (*s3.PutObjectOutput, error) {
-	s3Client, bucketName, e := GetS3Client()
+// PutFile uploads the content with a single request. The passed metadata and content type, if any, are stored with the object.
+func PutFile(ctx context.Context, pathToFile string, content io.ReadSeeker, meta map[string]*string, contentType string, checkExists bool, errChan ...chan error) (*s3.PutObjectOutput, error) {
+	s3Client, bucketName, e := getS3Client(ctx)
 	if e != nil {
 		return nil, e
 	}
 
 	key := pathToFile
-	var obj *s3.PutObjectOutput
-	e = RetryCallback(func() error {
-		var err error
-		obj, err = s3Client.PutObject((&s3.PutObjectInput{}).
-			SetBucket(bucketName).
-			SetKey(key).
-			SetBody(content),
-		)
-		if err != nil {
-			if len(errChan) > 0 {
-				errChan[0] <- err
-			} else {
-				fmt.Println(" ## Trying to Put file:", key)
-			}
-		}
-		return err
-	}, 3, 2*time.Second)
+	input := (&s3.PutObjectInput{}).
+		SetBucket(bucketName).
+		SetKey(key).
+		SetBody(content).
+		SetMetadata(meta)
+	if contentType != "" {
+		input.SetContentType(contentType)
+	}
+	// Transient errors are retried by the client, that rewinds the content
+	obj, e := s3Client.PutObjectWithContext(ctx, input, throttleTransfer)
 	if e != nil {
-		return nil, fmt.Errorf("could not put object in bucket %s with key %s, \ncause: %s", bucketName, key, e.Error())
+		return nil, fmt.Errorf("could not put object in bucket %s with key %s, \ncause: %s", bucketName, key, sendUploadError(e, errChan...).Error())
 	}
 
 	if checkExists {
 		fmt.Println(" ## Waiting for file to be indexed...")
 		// Now stat Node to make sure it is indexed
-		e = RetryCallback(func() error {
-			_, ok := StatNode(pathToFile)
+		e = RetryCallback(ctx, func() error {
+			_, ok := StatNode(ctx, pathToFile)
 			if !ok {
 				return fmt.Errorf("cannot stat node just after PutFile operation")
 			}
@@ -108,9 +177,52 @@ func PutFile(pathToFile string, content io.ReadSeeker, checkExists bool, errChan
 	return obj, nil
 }
 
-func StatNode(pathToFile string) (*models.TreeNode, bool) {
+// PutStream uploads content of unknown length, e.g. the standard input, that is sent by parts of PartSize bytes
+// as soon as they are read. As S3 limits the number of parts, streams cannot exceed MaxPartsCount times PartSize.
+// Its content type is detected from the name of the file or its first bytes, unless ContentType is set.
+// If Encrypt is set, the content is encrypted before it is sent.
+func PutStream(ctx context.Context, pathToFile string, content io.Reader) error {
+	if Encrypt {
+		encrypted, meta, e := encryptionKey().encrypt(content, nil, -1)
+		if e != nil {
+			return e
+		}
+		return putStream(ctx, pathToFile, encrypted, PartSize, meta, encryptedContentType)
+	}
+	head, content, e := peek(content)
+	if e != nil {
+		return e
+	}
+	return putStream(ctx, pathToFile, content, PartSize, nil, detectContentType(pathToFile, head))
+}
 
-	ctx, client, e := GetApiClient()
+func putStream(ctx context.Context, pathToFile string, content io.Reader, partSize int64, meta map[string]*string, contentType string) error {
+	s3Client, bucketName, e := getS3Client(ctx)
+	if e != nil {
+		return e
+	}
+	uploader := s3manager.NewUploaderWithClient(s3Client, func(u *s3manager.Uploader) {
+		u.PartSize = partSize
+		u.Concurrency = PartsConcurrency
+		u.RequestOptions = []request.Option{refreshCredentials(configFrom(ctx)), throttleTransfer}
+	})
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(pathToFile),
+		// Hide the Seek method of the content if any: streams such as pipes cannot be rewound
+		Body:     struct{ io.Reader }{content},
+		Metadata: meta,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, e = uploader.UploadWithContext(ctx, input)
+	return e
+}
+
+func StatNode(ctx context.Context, pathToFile string) (*models.TreeNode, bool) {
+
+	client, e := getApiClient(ctx)
 	if e != nil {
 		return nil, false
 	}
@@ -126,36 +238,24 @@ func StatNode(pathToFile string) (*models.TreeNode, bool) {
 
 }
 
-func ListNodesPath(path string) ([]string, error) {
-	_, client, err := GetApiClient()
-	if err != nil {
-		return nil, err
-	}
-	params := tree_service.NewBulkStatNodesParams()
-	params.Body = &models.RestGetBulkMetaRequest{
-		Limit:     100,
-		NodePaths: []string{path},
-	}
-	res, e := client.TreeService.BulkStatNodes(params)
-	if e != nil {
-		return nil, e
-	}
+func ListNodesPath(ctx context.Context, path string) ([]string, error) {
 	var nodes []string
-	if len(res.Payload.Nodes) < 0 {
-		return nil, nil
-	}
-	for _, node := range res.Payload.Nodes {
+	e := WalkBulkMetaNode(ctx, path, func(node *models.TreeNode) error {
 		nodes = append(nodes, node.Path)
+		return nil
+	})
+	if e != nil {
+		return nil, e
 	}
 	return nodes, nil
 }
 
-func DeleteNode(paths []string) (jobUUIDs []string, e error) {
+func DeleteNode(ctx context.Context, paths []string) (jobUUIDs []string, e error) {
 	if len(paths) < 0 {
 		e = fmt.Errorf("no paths found to delete")
 		return
 	}
-	_, client, err := GetApiClient()
+	client, err := getApiClient(ctx)
 	if err != nil {
 		e = err
 		return
@@ -165,7 +265,7 @@ func DeleteNode(paths []string) (jobUUIDs []string, e error) {
 		nn = append(nn, &models.TreeNode{Path: p})
 	}
 
-	params := tree_service.NewDeleteNodesParams()
+	params := tree_service.NewDeleteNodesParamsWithContext(ctx)
 	params.Body = &models.RestDeleteNodesRequest{
 		Nodes: nn,
 	}
@@ -181,30 +281,67 @@ func DeleteNode(paths []string) (jobUUIDs []string, e error) {
 	return
 }
 
-func GetBulkMetaNode(path string) ([]*models.TreeNode, error) {
-	_, client, err := GetApiClient()
-	if err != nil {
-		return nil, err
+// GetBulkMetaNode retrieves all the nodes matching the passed path, typically the children of a folder with "folder/*".
+func GetBulkMetaNode(ctx context.Context, path string) ([]*models.TreeNode, error) {
+	var nodes []*models.TreeNode
+	e := WalkBulkMetaNode(ctx, path, func(node *models.TreeNode) error {
+		nodes = append(nodes, node)
+		return nil
+	})
+	if e != nil {
+		return nil, e
 	}
-	params := tree_service.NewBulkStatNodesParams()
-	params.Body = &models.RestGetBulkMetaRequest{
-		Limit:     100,
-		NodePaths: []string{path},
+	return nodes, nil
+}
+
+// WalkBulkMetaNode streams the nodes matching the passed path to the callback, requesting them from
+// the server page by page, so that huge folders never have to be held in memory at once.
+// It stops at the first error returned by the callback.
+func WalkBulkMetaNode(ctx context.Context, path string, callback func(node *models.TreeNode) error) error {
+	client, err := getApiClient(ctx)
+	if err != nil {
+		return err
 	}
-	res, e := client.TreeService.BulkStatNodes(params)
-	if e != nil {
-		return nil, err
+	var offset int32
+	for {
+		params := tree_service.NewBulkStatNodesParamsWithContext(ctx)
+		params.Body = &models.RestGetBulkMetaRequest{
+			Limit:     BulkPageSize,
+			Offset:    offset,
+			NodePaths: []string{path},
+		}
+		res, e := client.TreeService.BulkStatNodes(params)
+		if e != nil {
+			return e
+		}
+		for _, node := range res.Payload.Nodes {
+			if e := callback(node); e != nil {
+				return e
+			}
+		}
+
+		count := int32(len(res.Payload.Nodes))
+		if pg := res.Payload.Pagination; pg != nil && pg.Total > 0 {
+			// Rely on pagination info when the server provides it
+			if pg.NextOffset <= offset || pg.NextOffset >= pg.Total {
+				return nil
+			}
+			offset = pg.NextOffset
+		} else if count < BulkPageSize {
+			return nil
+		} else {
+			offset += count
+		}
 	}
-	return res.Payload.Nodes, nil
 }
 
-func TreeCreateNodes(nodes []*models.TreeNode) error {
-	_, client, err := GetApiClient()
+func TreeCreateNodes(ctx context.Context, nodes []*models.TreeNode) error {
+	client, err := getApiClient(ctx)
 	if err != nil {
 		return err
 
 	}
-	params := tree_service.NewCreateNodesParams()
+	params := tree_service.NewCreateNodesParamsWithContext(ctx)
 	params.Body = &models.RestCreateNodesRequest{
 		Nodes:     nodes,
 		Recursive: false,
@@ -218,55 +355,253 @@ func TreeCreateNodes(nodes []*models.TreeNode) error {
 	return nil
 }
 
-func uploadManager(path string, content io.ReadSeeker, computeMD5 bool, errChan ...chan error) error {
-	s3Client, bucketName, err := GetS3Client()
+// multipartSource is a local content that can be uploaded by parts and resumed later on: *os.File implements it.
+type multipartSource interface {
+	io.ReaderAt
+	Name() string
+	Stat() (os.FileInfo, error)
+}
+
+// uploadManager performs a multipart upload of the passed local file, with up to PartsConcurrency parts sent in parallel.
+// Upload ID and completed parts are stored in a local journal: if the upload fails, the parts that are
+// already on the server are listed and only the missing ones are sent on next try.
+// When VerifyTransfers is set, it returns the MD5 of each part, computed while they are sent.
+// If the context is cancelled, the multipart upload is aborted on the server and the journal is dropped.
+func uploadManager(ctx context.Context, path string, content multipartSource, meta map[string]*string, contentType string, computeMD5 bool, progress func(int64), errChan ...chan error) ([][]byte, error) {
+	s3Client, bucketName, err := getS3Client(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	sess, err := session.NewSession(&s3Client.Config)
+	stats, err := content.Stat()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	sess.Config.S3DisableContentMD5Validation = aws.Bool(true)
-
-	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
-		u.PartSize = 50 * 1024 * 1024
-		u.Concurrency = 3
-		u.RequestOptions = []request.Option{func(r *request.Request) {
-			// We call log.fatal inside the method if there is an error, no need to manage that here.
-			RefreshAndStoreIfRequired(DefaultConfig)
-
-			s3Config := getS3ConfigFromSdkConfig(DefaultConfig)
-			apiKey, _ := oidc.RetrieveToken(&DefaultConfig.SdkConfig)
-			r.Config.WithCredentials(credentials.NewStaticCredentials(apiKey, s3Config.ApiSecret, ""))
-		}}
-	})
+	size := stats.Size()
+	if size > MaxObjectSize {
+		return nil, fmt.Errorf("%s is too big: objects are limited to %d bytes", content.Name(), MaxObjectSize)
+	}
+	partSize := partSizeFor(size)
 
-	input := &s3manager.UploadInput{
-		Body:   aws.ReadSeekCloser(content),
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(path),
+	refresh := refreshCredentials(configFrom(ctx))
+	uploaded := make(map[int64]*s3.CompletedPart)
+	journal := loadUploadJournal(configFrom(ctx).Url, path)
+	if journal != nil && journal.matches(content.Name(), stats) {
+		parts, e := listUploadedParts(ctx, s3Client, bucketName, journal)
+		if e != nil {
+			// Upload is not known by the server anymore, start a new one
+			journal.remove()
+			journal = nil
+		} else {
+			uploaded = parts
+			partSize = journal.PartSize
+		}
+	} else if journal != nil {
+		// Local file has changed since the previous try: drop the corresponding upload.
+		abortUpload(s3Client, bucketName, journal, refresh)
+		journal = nil
 	}
 
-	if computeMD5 {
-		h := md5.New()
-		if _, err := io.Copy(h, content); err != nil {
-			return fmt.Errorf("could not copy md5: %v", err)
+	if journal == nil {
+		input := &s3.CreateMultipartUploadInput{
+			Bucket:   aws.String(bucketName),
+			Key:      aws.String(path),
+			Metadata: make(map[string]*string, len(meta)+1),
+		}
+		if contentType != "" {
+			input.ContentType = aws.String(contentType)
+		}
+		for k, v := range meta {
+			input.Metadata[k] = v
+		}
+		if computeMD5 {
+			h := md5.New()
+			if _, err := io.Copy(h, io.NewSectionReader(content, 0, size)); err != nil {
+				return nil, fmt.Errorf("could not copy md5: %v", err)
+			}
+			input.Metadata["content-md5"] = aws.String(fmt.Sprintf("%x", h.Sum(nil)))
+		}
+		out, err := s3Client.CreateMultipartUploadWithContext(ctx, input, refresh)
+		if err != nil {
+			return nil, sendUploadError(err, errChan...)
+		}
+		journal = newUploadJournal(configFrom(ctx).Url, path, content.Name(), stats, *out.UploadId, partSize)
+		if err := journal.save(); err != nil {
+			return nil, err
 		}
-		input.Metadata = map[string]*string{"content-md5": aws.String(fmt.Sprintf("%x", h.Sum(nil)))}
 	}
 
-	_, _ = content.Seek(0, io.SeekStart)
+	numParts := (size + partSize - 1) / partSize
+	var partSums [][]byte
+	if VerifyTransfers {
+		partSums = make([][]byte, numParts)
+	}
+	skip := make(map[int64]bool)
+	for number, part := range uploaded {
+		skip[number] = journal.Parts[number] == *part.ETag
+	}
+	queue := make(chan int64)
+	wg := &sync.WaitGroup{}
+	errMux := &sync.Mutex{}
+	var firstErr error
+	setErr := func(e error) {
+		errMux.Lock()
+		if firstErr == nil {
+			firstErr = e
+		}
+		errMux.Unlock()
+	}
+	for w := 0; w < PartsConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range queue {
+				offset := (number - 1) * partSize
+				length := partSize
+				if offset+length > size {
+					length = size - offset
+				}
+				if skip[number] {
+					progress(length)
+					if partSums != nil {
+						sum, e := hashLocalSection(content, offset, length)
+						if e != nil {
+							setErr(e)
+						}
+						partSums[number-1] = sum
+					}
+					continue
+				}
+				body := &partReader{ReadSeeker: io.NewSectionReader(content, offset, length), progress: progress}
+				if partSums != nil {
+					body.hashes.sums = newChecksums(0)
+				}
+				out, e := s3Client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+					Bucket:     aws.String(bucketName),
+					Key:        aws.String(path),
+					UploadId:   aws.String(journal.UploadId),
+					PartNumber: aws.Int64(number),
+					Body:       body,
+				}, refresh, throttleTransfer)
+				if e == nil {
+					e = journal.partDone(number, *out.ETag)
+				}
+				if e == nil && partSums != nil {
+					partSums[number-1] = body.hashes.sums.md5.Sum(nil)
+				}
+				if e != nil {
+					setErr(e)
+				}
+			}
+		}()
+	}
+	for number := int64(1); number <= numParts; number++ {
+		errMux.Lock()
+		failed := firstErr != nil
+		errMux.Unlock()
+		if failed {
+			break
+		}
+		queue <- number
+	}
+	close(queue)
+	wg.Wait()
+	if ctx.Err() != nil {
+		abortUpload(s3Client, bucketName, journal, refresh)
+		return nil, ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, sendUploadError(firstErr, errChan...)
+	}
 
-	_, err = uploader.Upload(input)
+	completed := &s3.CompletedMultipartUpload{}
+	for number := int64(1); number <= numParts; number++ {
+		completed.Parts = append(completed.Parts, &s3.CompletedPart{
+			PartNumber: aws.Int64(number),
+			ETag:       aws.String(journal.Parts[number]),
+		})
+	}
+	_, err = s3Client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(path),
+		UploadId:        aws.String(journal.UploadId),
+		MultipartUpload: completed,
+	}, refresh)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			errChan[0] <- aerr
-			return aerr
+		return nil, sendUploadError(err, errChan...)
+	}
+	journal.remove()
+	return partSums, nil
+}
+
+// listUploadedParts retrieves the parts of a journaled upload that are already stored on the server.
+func listUploadedParts(ctx context.Context, s3Client *s3.S3, bucketName string, journal *uploadJournal) (map[int64]*s3.CompletedPart, error) {
+	parts := make(map[int64]*s3.CompletedPart)
+	e := s3Client.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(journal.Key),
+		UploadId: aws.String(journal.UploadId),
+	}, func(out *s3.ListPartsOutput, last bool) bool {
+		for _, p := range out.Parts {
+			parts[*p.PartNumber] = &s3.CompletedPart{PartNumber: p.PartNumber, ETag: p.ETag}
 		}
+		return true
+	}, refreshCredentials(configFrom(ctx)))
+	return parts, e
+}
+
+// abortUpload drops a journaled multipart upload and the parts that have already been sent.
+// It is not bound to the context of the transfer, so that it still runs after an interruption.
+func abortUpload(s3Client *s3.S3, bucketName string, journal *uploadJournal, refresh request.Option) {
+	_, _ = s3Client.AbortMultipartUploadWithContext(aws.BackgroundContext(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(journal.Key),
+		UploadId: aws.String(journal.UploadId),
+	}, refresh)
+	journal.remove()
+}
+
+// refreshCredentials returns a request option that renews the authentication token for the passed config
+// if required before sending a request.
+func refreshCredentials(conf *CecConfig) request.Option {
+	return func(r *request.Request) {
+		// We call log.fatal inside the method if there is an error, no need to manage that here.
+		RefreshAndStoreIfRequired(conf)
+
+		s3Config := getS3ConfigFromSdkConfig(conf)
+		apiKey, _ := oidc.RetrieveToken(&conf.SdkConfig)
+		r.Config.WithCredentials(credentials.NewStaticCredentials(apiKey, s3Config.ApiSecret, ""))
+	}
+}
+
+func sendUploadError(err error, errChan ...chan error) error {
+	if len(errChan) > 0 {
 		errChan[0] <- err
-		return err
 	}
-	return nil
+	return err
+}
+
+// partReader reports to the progress function the bytes that are read from a part.
+type partReader struct {
+	io.ReadSeeker
+	progress func(int64)
+	read     int64
+	hashes   hashedReader
+}
+
+func (r *partReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadSeeker.Read(p)
+	r.read += int64(n)
+	r.progress(int64(n))
+	r.hashes.read(p, n)
+	return
+}
+
+func (r *partReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		r.progress(pos - r.read)
+		r.read = pos
+		r.hashes.seek(pos)
+	}
+	return pos, err
 }
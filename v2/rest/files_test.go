@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pydio/cells-sdk-go/models"
+)
+
+func TestWalkBulkMetaNode(t *testing.T) {
+	stop := errors.New("stop")
+	tests := []struct {
+		name string
+		size int
+		// paginate sets the pagination info in the responses, next returns the NextOffset of the page at this offset
+		paginate bool
+		next     func(offset int32) int32
+		// stopAt makes the callback fail when it receives this node
+		stopAt   int
+		nodes    int
+		requests int
+	}{
+		{"pagination info", 250, true, func(o int32) int32 { return o + BulkPageSize }, -1, 250, 3},
+		{"no pagination info", 250, false, nil, -1, 250, 3},
+		{"full last page", 200, false, nil, -1, 200, 3},
+		{"empty folder", 0, false, nil, -1, 0, 1},
+		{"offset not moving forward", 250, true, func(o int32) int32 { return 0 }, -1, 100, 1},
+		{"callback error", 250, true, func(o int32) int32 { return o + BulkPageSize }, 120, 121, 2},
+	}
+	for _, tt := range tests {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			req := &models.RestGetBulkMetaRequest{}
+			if r.URL.Path != "/a/tree/stats" || json.NewDecoder(r.Body).Decode(req) != nil {
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+				return
+			}
+			res := &models.RestBulkMetaResponse{}
+			for i := req.Offset; i < req.Offset+req.Limit && int(i) < tt.size; i++ {
+				res.Nodes = append(res.Nodes, &models.TreeNode{Path: fmt.Sprintf("folder/%d", i)})
+			}
+			if tt.paginate {
+				res.Pagination = &models.RestPagination{Total: int32(tt.size), NextOffset: tt.next(req.Offset)}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(res)
+		}))
+
+		var nodes []string
+		e := WalkBulkMetaNode(testContext(server.URL), "folder/*", func(n *models.TreeNode) error {
+			nodes = append(nodes, n.Path)
+			if len(nodes)-1 == tt.stopAt {
+				return stop
+			}
+			return nil
+		})
+		server.Close()
+
+		if tt.stopAt >= 0 && e != stop || tt.stopAt < 0 && e != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, e)
+		}
+		if len(nodes) != tt.nodes || requests != tt.requests {
+			t.Errorf("%s: expected %d nodes in %d requests, got %d nodes in %d requests", tt.name, tt.nodes, tt.requests, len(nodes), requests)
+		}
+		for i, p := range nodes {
+			if p != fmt.Sprintf("folder/%d", i) {
+				t.Errorf("%s: node %d is %s", tt.name, i, p)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file that can be put at the root of a transferred folder
+// to list patterns of files and folders that must be ignored, with the same syntax as a .gitignore file.
+const IgnoreFileName = ".cecignore"
+
+// Filter decides which nodes are processed when walking a tree, using gitignore-style rules:
+// the last rule that matches a path wins, and a rule starting with '!' re-includes the matching paths.
+// A nil Filter only excludes hidden files and folders.
+type Filter struct {
+	// skipHidden excludes hidden files, ignoreRules and cmdRules are evaluated afterwards in this order.
+	skipHidden  bool
+	ignoreRules []*filterRule
+	cmdRules    []*filterRule
+}
+
+type filterRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// NewFilter creates a filter with the patterns that are passed on the command line. Include patterns
+// take precedence over exclude patterns. Unless includeHidden is true, files and folders whose name
+// starts with a dot are excluded, this can also be overridden by an include pattern.
+func NewFilter(excludes, includes []string, includeHidden bool) (*Filter, error) {
+	f := &Filter{skipHidden: !includeHidden}
+	var e error
+	if f.cmdRules, e = parsePatterns(excludes...); e != nil {
+		return nil, e
+	}
+	for _, i := range includes {
+		rr, e := parsePatterns("!" + strings.TrimPrefix(i, "!"))
+		if e != nil {
+			return nil, e
+		}
+		f.cmdRules = append(f.cmdRules, rr...)
+	}
+	return f, nil
+}
+
+// LoadIgnoreFile reads the rules defined in the ignore file found at the root of the passed folder, if any.
+// These rules are evaluated before the command line ones, that keep precedence.
+func (f *Filter) LoadIgnoreFile(ctx context.Context, root *CrawlNode) error {
+	if !root.IsDir {
+		return nil
+	}
+	var reader io.Reader
+	if root.IsLocal {
+		file, e := os.Open(filepath.Join(root.FullPath, IgnoreFileName))
+		if os.IsNotExist(e) {
+			return nil
+		} else if e != nil {
+			return e
+		}
+		defer file.Close()
+		reader = file
+	} else {
+		p := path.Join(root.FullPath, IgnoreFileName)
+		if _, ok := StatNode(ctx, p); !ok {
+			return nil
+		}
+		r, _, e := GetFile(ctx, p)
+		if e != nil {
+			return e
+		}
+		if closer, ok := r.(io.Closer); ok {
+			defer closer.Close()
+		}
+		reader = r
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if e := scanner.Err(); e != nil {
+		return e
+	}
+	rr, e := parsePatterns(lines...)
+	if e != nil {
+		return fmt.Errorf("invalid pattern in %s: %s", IgnoreFileName, e.Error())
+	}
+	f.ignoreRules = rr
+	return nil
+}
+
+// Excluded checks if the passed path, relative to the root of the walk and using '/' separators, must be ignored.
+func (f *Filter) Excluded(relPath string, isDir bool) bool {
+	relPath = strings.Trim(relPath, "/")
+	hidden := strings.HasPrefix(path.Base(relPath), ".")
+	if f == nil {
+		return hidden
+	}
+	excluded := f.skipHidden && hidden
+	for _, rr := range [][]*filterRule{f.ignoreRules, f.cmdRules} {
+		for _, r := range rr {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(relPath) {
+				excluded = !r.negate
+			}
+		}
+	}
+	return excluded
+}
+
+// parsePatterns converts gitignore-style lines to rules. Blank lines and comments are ignored.
+func parsePatterns(patterns ...string) (rules []*filterRule, err error) {
+	for _, p := range patterns {
+		p = strings.TrimRight(p, " \t\r")
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		r := &filterRule{pattern: p}
+		if strings.HasPrefix(p, "!") {
+			r.negate = true
+			p = p[1:]
+		} else if strings.HasPrefix(p, `\`) {
+			p = p[1:]
+		}
+		if strings.HasSuffix(p, "/") {
+			r.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+		if p == "" {
+			continue
+		}
+		// Patterns without any inner separator match the name at any level of the tree
+		anchored := strings.Contains(p, "/")
+		p = strings.TrimPrefix(p, "/")
+		expr := globToRegexp(p)
+		if anchored {
+			expr = "^" + expr + "$"
+		} else {
+			expr = "(^|/)" + expr + "$"
+		}
+		re, e := regexp.Compile(expr)
+		if e != nil {
+			return nil, fmt.Errorf("cannot parse pattern %s: %s", r.pattern, e.Error())
+		}
+		r.re = re
+		rules = append(rules, r)
+	}
+	return
+}
+
+// globToRegexp converts a gitignore glob into a regular expression, '**' matching across folders.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(glob[i+1:], ']'); end >= 0 {
+				class := glob[i+1 : i+1+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				b.WriteString("[" + class + "]")
+				i += end + 1
+			} else {
+				b.WriteString(`\[`)
+			}
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
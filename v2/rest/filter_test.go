@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob    string
+		matches []string
+		misses  []string
+	}{
+		{"*.log", []string{"a.log", ".log"}, []string{"a.logs", "d/a.log"}},
+		{"file?.txt", []string{"file1.txt"}, []string{"file10.txt", "file/.txt"}},
+		{"**/logs", []string{"logs", "a/logs", "a/b/logs"}, []string{"alogs", "logs/a"}},
+		{"docs/**", []string{"docs/a", "docs/a/b.txt"}, []string{"docs", "src/docs/a"}},
+		{"a/**/b", []string{"a/b", "a/x/b", "a/x/y/b"}, []string{"ab", "a/xb"}},
+		{"[ab].txt", []string{"a.txt", "b.txt"}, []string{"c.txt"}},
+		{"[!a].txt", []string{"b.txt"}, []string{"a.txt"}},
+		{"[unclosed", []string{"[unclosed"}, []string{"u"}},
+		{`\*.txt`, []string{"*.txt"}, []string{"a.txt"}},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile("^" + globToRegexp(tt.glob) + "$")
+		for _, m := range tt.matches {
+			if !re.MatchString(m) {
+				t.Errorf("%s should match %s", tt.glob, m)
+			}
+		}
+		for _, m := range tt.misses {
+			if re.MatchString(m) {
+				t.Errorf("%s should not match %s", tt.glob, m)
+			}
+		}
+	}
+}
+
+func TestFilterExcluded(t *testing.T) {
+	tests := []struct {
+		name          string
+		ignoreFile    []string
+		excludes      []string
+		includes      []string
+		includeHidden bool
+		path          string
+		isDir         bool
+		excluded      bool
+	}{
+		{name: "hidden file", path: "/.env", excluded: true},
+		{name: "hidden folder child", path: "a/.git", isDir: true, excluded: true},
+		{name: "hidden included", includeHidden: true, path: ".env"},
+		{name: "hidden re-included by pattern", includes: []string{".env"}, path: ".env"},
+		{name: "name at any level", excludes: []string{"*.tmp"}, path: "a/b/c.tmp", excluded: true},
+		{name: "name not matching", excludes: []string{"*.tmp"}, path: "a/b/c.txt"},
+		{name: "anchored at root", excludes: []string{"/build"}, path: "build", isDir: true, excluded: true},
+		{name: "anchored not nested", excludes: []string{"/build"}, path: "src/build", isDir: true},
+		{name: "inner separator is anchored", excludes: []string{"src/gen"}, path: "lib/src/gen", isDir: true},
+		{name: "double star prefix", excludes: []string{"**/node_modules"}, path: "a/b/node_modules", isDir: true, excluded: true},
+		{name: "double star suffix", excludes: []string{"cache/**"}, path: "cache/x/y.bin", excluded: true},
+		{name: "double star middle", excludes: []string{"a/**/z.txt"}, path: "a/b/c/z.txt", excluded: true},
+		{name: "directory only on folder", excludes: []string{"out/"}, path: "x/out", isDir: true, excluded: true},
+		{name: "directory only on file", excludes: []string{"out/"}, path: "x/out"},
+		{name: "negation", excludes: []string{"*.log", "!keep.log"}, path: "d/keep.log"},
+		{name: "negation other file", excludes: []string{"*.log", "!keep.log"}, path: "d/other.log", excluded: true},
+		{name: "last rule wins", excludes: []string{"!keep.log", "*.log"}, path: "keep.log", excluded: true},
+		{name: "include wins over exclude", excludes: []string{"*.txt"}, includes: []string{"important.txt"}, path: "important.txt"},
+		{name: "command line wins over ignore file", ignoreFile: []string{"*.bak"}, includes: []string{"*.bak"}, path: "a.bak"},
+		{name: "ignore file rule", ignoreFile: []string{"# comment", "", "*.bak"}, path: "a.bak", excluded: true},
+		{name: "escaped comment", ignoreFile: []string{`\#notes`}, path: "#notes", excluded: true},
+		{name: "escaped negation", ignoreFile: []string{`\!important`}, path: "!important", excluded: true},
+	}
+	for _, tt := range tests {
+		f, e := NewFilter(tt.excludes, tt.includes, tt.includeHidden)
+		if e != nil {
+			t.Fatalf("%s: %s", tt.name, e.Error())
+		}
+		if f.ignoreRules, e = parsePatterns(tt.ignoreFile...); e != nil {
+			t.Fatalf("%s: %s", tt.name, e.Error())
+		}
+		if got := f.Excluded(tt.path, tt.isDir); got != tt.excluded {
+			t.Errorf("%s: Excluded(%s) = %v, expected %v", tt.name, tt.path, got, tt.excluded)
+		}
+	}
+}
+
+func TestNilFilterExcludesHidden(t *testing.T) {
+	var f *Filter
+	if !f.Excluded("a/.hidden", false) {
+		t.Error("hidden files must be excluded by a nil filter")
+	}
+	if f.Excluded("a/visible", false) {
+		t.Error("visible files must not be excluded by a nil filter")
+	}
+}
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -40,22 +41,22 @@ func BuildParams(source []string, targetFolder string, targetParent bool) string
 	return string(data)
 }
 
-func CopyJob(jsonParams string) (string, error) {
-	return RunJob("copy", jsonParams)
+func CopyJob(ctx context.Context, jsonParams string) (string, error) {
+	return RunJob(ctx, "copy", jsonParams)
 }
 
-func MoveJob(jsonParams string) (string, error) {
-	return RunJob("move", jsonParams)
+func MoveJob(ctx context.Context, jsonParams string) (string, error) {
+	return RunJob(ctx, "move", jsonParams)
 }
 
 // RunJob runs a job.
-func RunJob(jobName string, jsonParams string) (string, error) {
+func RunJob(ctx context.Context, jobName string, jsonParams string) (string, error) {
 
-	_, client, err := GetApiClient()
+	client, err := getApiClient(ctx)
 	if err != nil {
 		return "", err
 	}
-	param := jobs_service.NewUserCreateJobParams()
+	param := jobs_service.NewUserCreateJobParamsWithContext(ctx)
 	param.Body = &models.RestUserJobRequest{
 		JobName:        jobName,
 		JSONParameters: jsonParams,
@@ -70,8 +71,8 @@ func RunJob(jobName string, jsonParams string) (string, error) {
 }
 
 // GetTaskStatusForJob retrieves the task status, progress and message.
-func GetTaskStatusForJob(jobID string) (status models.JobsTaskStatus, msg string, pg float32, e error) {
-	_, client, err := GetApiClient()
+func GetTaskStatusForJob(ctx context.Context, jobID string) (status models.JobsTaskStatus, msg string, pg float32, e error) {
+	client, err := getApiClient(ctx)
 	if err != nil {
 		e = err
 		return
@@ -80,7 +81,7 @@ func GetTaskStatusForJob(jobID string) (status models.JobsTaskStatus, msg string
 		JobIds:    []string{jobID},
 		LoadTasks: models.JobsTaskStatusAny,
 	}
-	params := jobs_service.NewUserListJobsParams()
+	params := jobs_service.NewUserListJobsParamsWithContext(ctx)
 	params.Body = body
 	jobs, err := client.JobsService.UserListJobs(params)
 	if err != nil {
@@ -103,11 +104,11 @@ func GetTaskStatusForJob(jobID string) (status models.JobsTaskStatus, msg string
 	return
 }
 
-// MonitorJob monitors a job status every second.
-func MonitorJob(JobID string) (err error) {
+// MonitorJob monitors a job status every second, until it ends or the context is cancelled.
+func MonitorJob(ctx context.Context, JobID string) (err error) {
 	for {
-		status, _, _, e := GetTaskStatusForJob(JobID)
-		if err != nil {
+		status, _, _, e := GetTaskStatusForJob(ctx, JobID)
+		if e != nil {
 			err = e
 			return
 		}
@@ -115,7 +116,12 @@ func MonitorJob(JobID string) (err error) {
 		switch status {
 		case models.JobsTaskStatusRunning, models.JobsTaskStatusPaused, models.JobsTaskStatusQueued:
 			//fmt.Println("running, progress: ", pg)
-			<-time.After(500 * time.Millisecond)
+			select {
+			case <-time.After(500 * time.Millisecond):
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
 
 		case models.JobsTaskStatusError:
 			err = fmt.Errorf("JobTask status error, %s", status)
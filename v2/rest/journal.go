@@ -0,0 +1,189 @@
+package rest
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// uploadJournal keeps track of an ongoing multipart upload on the local file system,
+// so that it can be resumed by a later run of the same command if it has been interrupted.
+type uploadJournal struct {
+	Key      string
+	Source   string
+	Size     int64
+	ModTime  int64
+	UploadId string
+	PartSize int64
+	Parts    map[int64]string
+
+	file string
+	mux  sync.Mutex
+}
+
+const (
+	uploadsFolder   = "uploads"
+	downloadsFolder = "downloads"
+)
+
+// journalsFolder returns the folder where upload or download journals are stored, next to the config file.
+func journalsFolder(kind string) string {
+	return filepath.Join(filepath.Dir(GetConfigFilePath()), kind)
+}
+
+// journalFile computes a stable file name for the given key on the passed server.
+func journalFile(kind, url, key string) string {
+	h := sha1.Sum([]byte(url + "::" + key))
+	return filepath.Join(journalsFolder(kind), fmt.Sprintf("%x.json", h))
+}
+
+func newUploadJournal(url, key, source string, info os.FileInfo, uploadId string, partSize int64) *uploadJournal {
+	return &uploadJournal{
+		Key:      key,
+		Source:   source,
+		Size:     info.Size(),
+		ModTime:  info.ModTime().Unix(),
+		UploadId: uploadId,
+		PartSize: partSize,
+		Parts:    make(map[int64]string),
+		file:     journalFile(uploadsFolder, url, key),
+	}
+}
+
+// loadUploadJournal retrieves the journal of a previous upload for this key, if any.
+func loadUploadJournal(url, key string) *uploadJournal {
+	f := journalFile(uploadsFolder, url, key)
+	j := &uploadJournal{}
+	if !readJournal(f, j) {
+		return nil
+	}
+	if j.Parts == nil {
+		j.Parts = make(map[int64]string)
+	}
+	j.file = f
+	return j
+}
+
+// matches checks that the local source has not changed since the journal has been created.
+func (j *uploadJournal) matches(source string, info os.FileInfo) bool {
+	return j.Source == source && j.Size == info.Size() && j.ModTime == info.ModTime().Unix()
+}
+
+// partDone registers the ETag of a successfully uploaded part and persists the journal.
+func (j *uploadJournal) partDone(number int64, eTag string) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	j.Parts[number] = eTag
+	return j.save()
+}
+
+func (j *uploadJournal) save() error {
+	return writeJournal(j.file, j)
+}
+
+func (j *uploadJournal) remove() {
+	_ = os.Remove(j.file)
+}
+
+// downloadJournal keeps track of the parts of a parallel download that have already been written in the
+// '.part' file. As parts are written at their own offset, the file is not a valid prefix of the remote file:
+// the size and modification time of the '.part' file are recorded with the parts, so that the journal is
+// not trusted if the file has been modified, replaced or removed since then.
+type downloadJournal struct {
+	Target   string
+	Source   string
+	Size     int64
+	ModTime  int64
+	PartSize int64
+	Parts    map[int64]bool
+	// FileSize and FileModTime identify the '.part' file when the journal has been saved.
+	FileSize    int64
+	FileModTime int64
+
+	file string
+	mux  sync.Mutex
+}
+
+func newDownloadJournal(url, target string, src *CrawlNode, partSize int64) *downloadJournal {
+	return &downloadJournal{
+		Target:   target,
+		Source:   src.FullPath,
+		Size:     src.Size,
+		ModTime:  src.MTime.Unix(),
+		PartSize: partSize,
+		Parts:    make(map[int64]bool),
+		file:     journalFile(downloadsFolder, url, target),
+	}
+}
+
+// loadDownloadJournal retrieves the journal of a previous parallel download to this '.part' file, if any.
+func loadDownloadJournal(url, target string) *downloadJournal {
+	f := journalFile(downloadsFolder, url, target)
+	j := &downloadJournal{}
+	if !readJournal(f, j) {
+		return nil
+	}
+	if j.Parts == nil {
+		j.Parts = make(map[int64]bool)
+	}
+	j.file = f
+	return j
+}
+
+// matches checks that neither the remote file nor the '.part' file have changed since the journal has been saved.
+func (j *downloadJournal) matches(src *CrawlNode, part os.FileInfo) bool {
+	return j.Source == src.FullPath && j.Size == src.Size && j.ModTime == src.MTime.Unix() &&
+		j.FileSize == part.Size() && j.FileModTime == part.ModTime().UnixNano()
+}
+
+// partDone registers a part that has been written in the file and persists the journal.
+func (j *downloadJournal) partDone(number int64, file *os.File) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	j.Parts[number] = true
+	return j.saveWith(file)
+}
+
+// saveWith records the current state of the '.part' file and persists the journal.
+func (j *downloadJournal) saveWith(file *os.File) error {
+	info, e := file.Stat()
+	if e != nil {
+		return e
+	}
+	j.FileSize = info.Size()
+	j.FileModTime = info.ModTime().UnixNano()
+	return writeJournal(j.file, j)
+}
+
+func (j *downloadJournal) remove() {
+	_ = os.Remove(j.file)
+}
+
+// readJournal loads a journal file, it returns false if there is none or if it is corrupted.
+func readJournal(f string, j interface{}) bool {
+	data, e := ioutil.ReadFile(f)
+	if e != nil {
+		return false
+	}
+	if e := json.Unmarshal(data, j); e != nil {
+		// Corrupted journal, forget about it
+		_ = os.Remove(f)
+		return false
+	}
+	return true
+}
+
+func writeJournal(f string, j interface{}) error {
+	if e := os.MkdirAll(filepath.Dir(f), 0700); e != nil {
+		return e
+	}
+	data, e := json.Marshal(j)
+	if e != nil {
+		return e
+	}
+	return ioutil.WriteFile(f, data, 0600)
+}
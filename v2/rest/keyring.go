@@ -1,11 +1,10 @@
 package rest
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/zalando/go-keyring"
-
-	cells_sdk "github.com/pydio/cells-sdk-go"
 )
 
 const (
@@ -14,12 +13,28 @@ const (
 	keyringClientCredentialsKey = "ClientCredentials"
 )
 
+// secretKey builds the key under which a secret of the config is stored. Configs that are not stored in the
+// default config file are also identified by their file, so that configs of the same server do not share their secrets.
+func secretKey(conf *CecConfig, secret string) string {
+	p := conf.filePath
+	if p == "" {
+		p = GetConfigFilePath()
+	}
+	if abs, e := filepath.Abs(p); e == nil {
+		p = abs
+	}
+	if p == DefaultConfigFilePath() {
+		return conf.Url + "::" + secret
+	}
+	return conf.Url + "::" + p + "::" + secret
+}
+
 // ConfigToKeyring tries to store tokens in local keychain and remove them from the conf
 func ConfigToKeyring(conf *CecConfig) error {
 
 	// We use OAuth2 grant flow
 	if conf.IdToken != "" && conf.RefreshToken != "" {
-		key := conf.Url + "::" + keyringIdTokenKey
+		key := secretKey(conf, keyringIdTokenKey)
 		value := conf.IdToken + "__//__" + conf.RefreshToken
 		if e := keyring.Set(keyringService, key, value); e != nil {
 			return e
@@ -30,7 +45,7 @@ func ConfigToKeyring(conf *CecConfig) error {
 
 	// We use client credentials
 	if conf.ClientSecret != "" && conf.Password != "" {
-		key := conf.Url + "::" + keyringClientCredentialsKey
+		key := secretKey(conf, keyringClientCredentialsKey)
 		value := conf.ClientSecret + "__//__" + conf.Password
 		if e := keyring.Set(keyringService, key, value); e != nil {
 			return e
@@ -47,7 +62,7 @@ func ConfigFromKeyring(conf *CecConfig) error {
 
 	// If only client key and user name, consider Client Secret and password are in the keyring
 	if conf.ClientKey != "" && conf.ClientSecret == "" && conf.User != "" && conf.Password == "" {
-		if value, e := keyring.Get(keyringService, conf.Url+"::"+keyringClientCredentialsKey); e == nil {
+		if value, e := keyring.Get(keyringService, secretKey(conf, keyringClientCredentialsKey)); e == nil {
 			parts := strings.Split(value, "__//__")
 			conf.ClientSecret = parts[0]
 			conf.Password = parts[1]
@@ -58,7 +73,7 @@ func ConfigFromKeyring(conf *CecConfig) error {
 
 	// If no token, no user and no client key, consider tokens are stored in keyring
 	if conf.IdToken == "" && conf.RefreshToken == "" && conf.User == "" && conf.Password == "" {
-		if value, e := keyring.Get(keyringService, conf.Url+"::"+keyringIdTokenKey); e == nil {
+		if value, e := keyring.Get(keyringService, secretKey(conf, keyringIdTokenKey)); e == nil {
 			parts := strings.Split(value, "__//__")
 			conf.IdToken = parts[0]
 			conf.RefreshToken = parts[1]
@@ -70,15 +85,15 @@ func ConfigFromKeyring(conf *CecConfig) error {
 }
 
 // ClearKeyring removes sensitive info from local keychain, if they are present.
-func ClearKeyring(c *cells_sdk.SdkConfig) error {
+func ClearKeyring(c *CecConfig) error {
 	// Best effort to remove known keys from keyring
 	// TODO maybe check if at least one of the two has been found and deleted and otherwise print at least a warning
-	if err := keyring.Delete(keyringService, c.Url+"::"+keyringClientCredentialsKey); err != nil {
+	if err := keyring.Delete(keyringService, secretKey(c, keyringClientCredentialsKey)); err != nil {
 		if err.Error() != "secret not found in keyring" {
 			return err
 		}
 	}
-	if err := keyring.Delete(keyringService, c.Url+"::"+keyringIdTokenKey); err != nil {
+	if err := keyring.Delete(keyringService, secretKey(c, keyringIdTokenKey)); err != nil {
 		if err.Error() != "secret not found in keyring" {
 			return err
 		}
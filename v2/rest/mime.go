@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+)
+
+// ContentType overrides the MIME type of the uploaded files when it is set, otherwise it is detected.
+var ContentType string
+
+// sniffLength is the number of bytes that are considered to detect the type of a content.
+const sniffLength = 512
+
+// ParseContentType validates a MIME type passed on the command line.
+func ParseContentType(s string) (string, error) {
+	if _, _, e := mime.ParseMediaType(s); e != nil {
+		return "", fmt.Errorf("invalid content type %s: %s", s, e.Error())
+	}
+	return s, nil
+}
+
+// detectContentType returns the MIME type of a file from the extension of its name, or from its first bytes
+// if the extension is unknown. It returns an empty string when the type cannot be determined.
+func detectContentType(name string, head []byte) string {
+	if ContentType != "" {
+		return ContentType
+	}
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	if len(head) == 0 {
+		return ""
+	}
+	return http.DetectContentType(head)
+}
+
+// peek reads the first bytes of a stream and returns a reader that yields the whole stream again.
+func peek(r io.Reader) ([]byte, io.Reader, error) {
+	head := make([]byte, sniffLength)
+	n, e := io.ReadFull(r, head)
+	if e != nil && e != io.EOF && e != io.ErrUnexpectedEOF {
+		return nil, nil, e
+	}
+	head = head[:n]
+	return head, io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// Peek returns the first bytes of the content, that will still be read afterwards. As it does not
+// go through the progress bar nor the checksums, it must be called before the content is read.
+func (r *PgReader) Peek() ([]byte, error) {
+	if r.Seeker == nil {
+		head, reader, e := peek(r.Reader)
+		if e != nil {
+			return nil, e
+		}
+		r.Reader = reader
+		return head, nil
+	}
+	head, _, e := peek(r.Reader)
+	if e != nil {
+		return nil, e
+	}
+	if _, e := r.Seeker.Seek(0, io.SeekStart); e != nil {
+		return nil, e
+	}
+	return head, nil
+}
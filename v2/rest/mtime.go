@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// PreserveTimes makes uploads store the modification time of the local files in the metadata of the objects,
+// and downloads set the modification time of the local files to the one of the remote files.
+var PreserveTimes bool
+
+// mTimeMeta is the S3 metadata that stores the original modification time of a file, in seconds since epoch.
+const mTimeMeta = "Mtime"
+
+// timeMetadata returns the metadata to send with the content of a file modified at the passed time, if times are preserved.
+func timeMetadata(mTime time.Time) map[string]*string {
+	if !PreserveTimes || mTime.IsZero() {
+		return nil
+	}
+	return map[string]*string{mTimeMeta: aws.String(strconv.FormatInt(mTime.Unix(), 10))}
+}
+
+// preservedMTime returns the original modification time stored in the metadata of an object uploaded with
+// PreserveTimes, or the passed default time.
+func preservedMTime(meta map[string]*string, def time.Time) time.Time {
+	if sec, e := strconv.ParseInt(metadata(meta, mTimeMeta), 10, 64); e == nil {
+		return time.Unix(sec, 0)
+	}
+	return def
+}
+
+// originalMTime returns the modification time of the file as it was on the local disk when it has been uploaded
+// with PreserveTimes, or the modification time of the node otherwise.
+func (c *CrawlNode) originalMTime(ctx context.Context) time.Time {
+	if c.IsLocal || c.IsDir {
+		return c.MTime
+	}
+	h, e := c.objectInfo(ctx)
+	if e != nil {
+		return c.MTime
+	}
+	return preservedMTime(h.Metadata, c.MTime)
+}
@@ -0,0 +1,201 @@
+package rest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gosuri/uiprogress"
+)
+
+// ProgressMode defines how the progress of transfers is displayed.
+type ProgressMode string
+
+const (
+	// ProgressAuto renders bars when the output is a terminal, and log lines otherwise.
+	ProgressAuto ProgressMode = "auto"
+	// ProgressBars always renders interactive progress bars.
+	ProgressBars ProgressMode = "bars"
+	// ProgressLog prints one line when each file starts and finishes, plus periodic aggregate lines.
+	ProgressLog ProgressMode = "log"
+)
+
+var (
+	// ProgressDisplay is the mode used by the BarsPool that are started afterwards.
+	ProgressDisplay = ProgressAuto
+	// ProgressLogInterval is the period of the aggregate lines in log mode.
+	ProgressLogInterval = 10 * time.Second
+)
+
+// ParseProgressMode validates the mode passed on the command line.
+func ParseProgressMode(s string) (ProgressMode, error) {
+	switch m := ProgressMode(strings.ToLower(s)); m {
+	case ProgressAuto, ProgressBars, ProgressLog:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown progress mode %s, use auto, bars or log", s)
+}
+
+// Start renders the progress bars, or starts printing aggregate log lines if the output is not a terminal.
+func (b *BarsPool) Start() {
+	b.mux.Lock()
+	b.started = time.Now()
+	b.logMode = ProgressDisplay == ProgressLog || (ProgressDisplay == ProgressAuto && !isTerminal(b.Out))
+	b.mux.Unlock()
+	if !b.logMode {
+		b.Progress.Start()
+		return
+	}
+	b.Summary.onRecord = b.logFileDone
+	b.stopLog = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ProgressLogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.logProgress()
+			case <-b.stopLog:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops rendering the bars, or prints a last aggregate line in log mode.
+func (b *BarsPool) Stop() {
+	if !b.logMode {
+		b.Progress.Stop()
+		return
+	}
+	b.mux.Lock()
+	stopped := b.stopLog == nil
+	stopLog := b.stopLog
+	b.stopLog = nil
+	b.mux.Unlock()
+	if !stopped {
+		close(stopLog)
+		b.logProgress()
+	}
+}
+
+// stats computes the number of bytes that have been transferred and the ones that remain, as well as
+// the average rate since the pool has been started. The remaining bytes are unknown while discovering.
+func (b *BarsPool) stats() (done, total int64, rate float64, known bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	done = b.doneBytes
+	for bar := range b.fileBars {
+		done += int64(bar.Current())
+	}
+	if elapsed := time.Since(b.started).Seconds(); elapsed > 0 {
+		rate = float64(done) / elapsed
+	}
+	return done, b.totalBytes, rate, !b.discovering && b.totalBytes > 0
+}
+
+// fileDone stops tracking the bar of a file whose transfer is over, its bytes are added to the running total.
+func (b *BarsPool) fileDone(bar *uiprogress.Bar) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if _, ok := b.fileBars[bar]; ok {
+		delete(b.fileBars, bar)
+		b.doneBytes += int64(bar.Current())
+	}
+}
+
+// eta renders the estimated remaining time at the given rate.
+func eta(remaining int64, rate float64, known bool) string {
+	if !known || rate <= 0 {
+		return "unknown"
+	}
+	if remaining <= 0 {
+		return "0s"
+	}
+	return (time.Duration(float64(remaining)/rate) * time.Second).Round(time.Second).String()
+}
+
+// formatBytes renders a size without spaces, so that log lines are easy to parse.
+func formatBytes(n int64) string {
+	if n < 0 {
+		n = 0
+	}
+	return strings.ReplaceAll(humanize.Bytes(uint64(n)), " ", "")
+}
+
+// globalStats renders the rate and ETA of the whole transfer for the global bar.
+func (b *BarsPool) globalStats() string {
+	done, total, rate, known := b.stats()
+	return fmt.Sprintf("%s/s, ETA %s", formatBytes(int64(rate)), eta(total-done, rate, known))
+}
+
+// barStats renders the rate and ETA of a single file, whose transfer started at the given time.
+func barStats(bar *uiprogress.Bar, started time.Time) string {
+	var rate float64
+	if elapsed := time.Since(started).Seconds(); elapsed > 0 {
+		rate = float64(bar.Current()) / elapsed
+	}
+	return fmt.Sprintf("%s/s, ETA %s", formatBytes(int64(rate)), eta(int64(bar.Total-bar.Current()), rate, true))
+}
+
+// logLine prints a timestamped line of key=value pairs.
+func (b *BarsPool) logLine(event string, pairs ...interface{}) {
+	var sb strings.Builder
+	sb.WriteString(time.Now().Format(time.RFC3339))
+	sb.WriteString(" " + event)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fmt.Fprintf(&sb, " %s=%v", pairs[i], pairs[i+1])
+	}
+	fmt.Fprintln(b.Out, sb.String())
+}
+
+// files renders the number of files done out of the total number of files known so far.
+func (b *BarsPool) files() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return fmt.Sprintf("%d/%d", b.filesDone, b.filesTotal)
+}
+
+func (b *BarsPool) logFileStart(name string, size int) {
+	_, _, rate, _ := b.stats()
+	b.logLine("start", "file", fmt.Sprintf("%q", name), "size", formatBytes(int64(size)), "files", b.files(), "rate", formatBytes(int64(rate))+"/s")
+}
+
+func (b *BarsPool) logFileDone(r *FileReport) {
+	if r.Status == StatusIgnored || r.Status == StatusNotStarted {
+		return
+	}
+	b.mux.Lock()
+	b.filesDone++
+	b.mux.Unlock()
+	done, total, rate, known := b.stats()
+	var fileRate float64
+	if r.Duration > 0 {
+		fileRate = float64(r.Size) / r.Duration
+	}
+	pairs := []interface{}{"file", fmt.Sprintf("%q", r.Source), "status", r.Status, "size", formatBytes(r.Size),
+		"duration", (time.Duration(r.Duration * float64(time.Second))).Round(time.Millisecond), "fileRate", formatBytes(int64(fileRate)) + "/s",
+		"files", b.files(), "rate", formatBytes(int64(rate)) + "/s", "eta", eta(total-done, rate, known)}
+	if r.Error != "" {
+		pairs = append(pairs, "error", fmt.Sprintf("%q", r.Error))
+	}
+	b.logLine("done", pairs...)
+}
+
+func (b *BarsPool) logProgress() {
+	done, total, rate, known := b.stats()
+	b.logLine("progress", "files", b.files(), "bytes", formatBytes(done)+"/"+formatBytes(total), "rate", formatBytes(int64(rate))+"/s", "eta", eta(total-done, rate, known))
+}
+
+// isTerminal checks if the writer is a terminal, in which case bars can be rendered.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	i, e := f.Stat()
+	return e == nil && i.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,264 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/go-openapi/runtime"
+)
+
+// RetryPolicy defines how requests that failed with a transient error are sent again:
+// the delay between two attempts grows exponentially, with a random jitter so that parallel
+// transfers do not hammer the server at the same time.
+type RetryPolicy struct {
+	// Retries is the maximum number of times a failed request is sent again, 0 disables retries.
+	Retries int
+	// MinDelay is the delay before the first retry, it is doubled at each new attempt.
+	MinDelay time.Duration
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration
+}
+
+// Retry is the policy that applies to all the requests sent to the server, both with the REST API and S3.
+var Retry = RetryPolicy{Retries: 5, MinDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+
+// Delay computes the wait before the retry that follows the passed attempt (starting at 0): a random
+// duration between half and the whole of the exponential delay.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := p.MaxDelay
+	if attempt < 32 && p.MinDelay<<uint(attempt) < p.MaxDelay {
+		d = p.MinDelay << uint(attempt)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable, or the retries are exhausted.
+// Errors wrapped with permanent are returned as is, without any retry.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		e := fn()
+		if pe, ok := e.(*permanentError); ok {
+			return pe.error
+		}
+		if e == nil || attempt >= p.Retries || !IsRetryable(e) {
+			return e
+		}
+		if e := p.wait(ctx, p.Delay(attempt)); e != nil {
+			return e
+		}
+	}
+}
+
+// wait sleeps for the passed delay, unless the context is cancelled before.
+func (p RetryPolicy) wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// permanentError stops RetryPolicy.Do, typically for a request that has already been retried by the client.
+type permanentError struct {
+	error
+}
+
+func permanent(e error) error {
+	if e == nil {
+		return nil
+	}
+	return &permanentError{e}
+}
+
+// IsRetryable tells whether an error is transient and the failed request can be sent again:
+// server errors (5xx), throttling (429), connections that have been reset or closed, and timeouts.
+// Any other error, e.g. a missing file, a denied access or the end of a local reader, is fatal.
+func IsRetryable(e error) bool {
+	if e == nil || errors.Is(e, context.Canceled) || errors.Is(e, context.DeadlineExceeded) {
+		return false
+	}
+	if rf, ok := e.(awserr.RequestFailure); ok && rf.StatusCode() > 0 {
+		return retryableStatus(rf.StatusCode())
+	}
+	if ae, ok := e.(awserr.Error); ok {
+		switch ae.Code() {
+		case request.CanceledErrorCode:
+			return false
+		case "RequestTimeout", "RequestTimeoutException", "SlowDown", "Throttling", "ThrottlingException":
+			return true
+		}
+		if orig := ae.OrigErr(); orig != nil {
+			return IsRetryable(orig)
+		}
+		return ae.Code() == request.ErrCodeRequestError || ae.Code() == request.ErrCodeRead || ae.Code() == request.ErrCodeResponseTimeout
+	}
+	var apiErr *runtime.APIError
+	if errors.As(e, &apiErr) {
+		return retryableStatus(apiErr.Code)
+	}
+	var netErr net.Error
+	if errors.As(e, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(e, syscall.ECONNRESET) || errors.Is(e, syscall.ECONNABORTED) || errors.Is(e, syscall.EPIPE) ||
+		errors.Is(e, io.ErrUnexpectedEOF) {
+		return true
+	}
+	// A request fails with EOF when the server closes a connection that is being reused
+	var urlErr *url.Error
+	if errors.As(e, &urlErr) && errors.Is(urlErr.Err, io.EOF) {
+		return true
+	}
+	// Some layers only keep the message of the original error
+	msg := e.Error()
+	return strings.Contains(msg, "connection reset by peer") || strings.Contains(msg, "broken pipe") || strings.Contains(msg, "unexpected EOF")
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500 && code != http.StatusNotImplemented
+}
+
+// isDialError checks if the connection to the server could not be established, in which case
+// the request has not been sent at all.
+func isDialError(e error) bool {
+	var opErr *net.OpError
+	return errors.As(e, &opErr) && opErr.Op == "dial"
+}
+
+// readOnlyPosts lists the REST endpoints that are called with POST to pass a query, but do not modify anything.
+var readOnlyPosts = []string{
+	"/tree/stats",       // BulkStatNodes
+	"/tree/admin/list",  // ListAdminTree
+	"/tree/admin/stat",  // StatAdminTree
+	"/jobs/user",        // UserListJobs
+	"/jobs/tasks/logs",  // ListTasksLogs
+	"/search/nodes",     // Nodes
+	"/user-meta/search", // SearchUserMeta
+	"/meta/bulk/get",    // GetBulkMeta
+}
+
+// isIdempotent checks if a request can be sent again although the server may already have processed it.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		for _, p := range readOnlyPosts {
+			if strings.HasSuffix(req.URL.Path, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// s3Retryer applies the retry policy to the requests of the S3 client, that rewinds the bodies itself.
+type s3Retryer struct {
+	policy RetryPolicy
+}
+
+func (r s3Retryer) RetryRules(req *request.Request) time.Duration {
+	return r.policy.Delay(req.RetryCount)
+}
+
+// ShouldRetry applies the same rule as the REST client: requests that create, complete or abort a multipart
+// upload may already have been processed by the server, they are only sent again if it could not be reached.
+func (r s3Retryer) ShouldRetry(req *request.Request) bool {
+	switch req.Operation.HTTPMethod {
+	case http.MethodGet, http.MethodHead, http.MethodPut:
+		return IsRetryable(req.Error)
+	}
+	return isS3DialError(req.Error)
+}
+
+// isS3DialError checks if the cause of an error of the S3 client is a connection that could not be established.
+func isS3DialError(e error) bool {
+	for e != nil {
+		if isDialError(e) {
+			return true
+		}
+		ae, ok := e.(awserr.Error)
+		if !ok {
+			return false
+		}
+		e = ae.OrigErr()
+	}
+	return false
+}
+
+func (r s3Retryer) MaxRetries() int {
+	return r.policy.Retries
+}
+
+// retryTransport applies the retry policy to the requests of the REST API client. Requests that may modify
+// something on the server, e.g. creating nodes or starting a job, are only sent again if the connection
+// could not be established, so that they are never processed twice. Requests whose body cannot be read
+// again are sent only once.
+type retryTransport struct {
+	rt     http.RoundTripper
+	policy RetryPolicy
+}
+
+func newRetryTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &retryTransport{rt: rt, policy: Retry}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return t.rt.RoundTrip(req)
+	}
+	idempotent := isIdempotent(req)
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, e := req.GetBody()
+			if e != nil {
+				return nil, e
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, e := t.rt.RoundTrip(r)
+		if attempt >= t.policy.Retries {
+			return resp, e
+		}
+		delay := t.policy.Delay(attempt)
+		if e != nil {
+			if idempotent && !IsRetryable(e) || !idempotent && !isDialError(e) {
+				return resp, e
+			}
+		} else if idempotent && retryableStatus(resp.StatusCode) {
+			if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+				if after := time.Duration(s) * time.Second; after < t.policy.MaxDelay {
+					delay = after
+				}
+			}
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+			_ = resp.Body.Close()
+		} else {
+			return resp, nil
+		}
+		if e := t.policy.wait(req.Context(), delay); e != nil {
+			return nil, e
+		}
+	}
+}
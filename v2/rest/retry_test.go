@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+func TestS3RetryerShouldRetry(t *testing.T) {
+	dialErr := awserr.New(request.ErrCodeRequestError, "send request failed",
+		&url.Error{Op: "Post", URL: "http://cells", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}})
+	resetErr := awserr.New(request.ErrCodeRequestError, "send request failed",
+		&url.Error{Op: "Post", URL: "http://cells", Err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}})
+	serverErr := awserr.NewRequestFailure(awserr.New("InternalError", "internal error", nil), 500, "")
+	notFound := awserr.NewRequestFailure(awserr.New("NoSuchKey", "not found", nil), 404, "")
+	tests := []struct {
+		operation string
+		method    string
+		err       error
+		retry     bool
+	}{
+		{"GetObject", "GET", serverErr, true},
+		{"HeadObject", "HEAD", resetErr, true},
+		{"UploadPart", "PUT", serverErr, true},
+		{"GetObject", "GET", notFound, false},
+		{"CreateMultipartUpload", "POST", dialErr, true},
+		{"CreateMultipartUpload", "POST", serverErr, false},
+		{"CompleteMultipartUpload", "POST", resetErr, false},
+		{"AbortMultipartUpload", "DELETE", serverErr, false},
+		{"AbortMultipartUpload", "DELETE", dialErr, true},
+	}
+	retryer := s3Retryer{policy: Retry}
+	for _, tt := range tests {
+		req := &request.Request{Operation: &request.Operation{Name: tt.operation, HTTPMethod: tt.method}, Error: tt.err}
+		if got := retryer.ShouldRetry(req); got != tt.retry {
+			t.Errorf("%s with %v: expected %v, got %v", tt.operation, tt.err, tt.retry, got)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status of a file in the transfer summary.
+const (
+	StatusTransferred = "transferred"
+	StatusSkipped     = "skipped"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+	StatusNotStarted  = "notStarted"
+	StatusIgnored     = "ignored"
+)
+
+// FileReport describes the outcome of the transfer of a single file.
+type FileReport struct {
+	Source string `json:"source"`
+	Target string `json:"target,omitempty"`
+	Size   int64  `json:"size"`
+	// Duration of the transfer, in seconds.
+	Duration float64 `json:"duration"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+
+	start time.Time
+}
+
+// newFileReport starts the report of the transfer of a source file to the target path.
+func newFileReport(src *CrawlNode, target string) *FileReport {
+	return &FileReport{Source: src.FullPath, Target: target, Size: src.Size, start: time.Now()}
+}
+
+// TransferSummary keeps track of the outcome of each file processed by a transfer.
+type TransferSummary struct {
+	Transferred int `json:"transferred"`
+	Skipped     int `json:"skipped"`
+	// Failed counts the files whose transfer returned an error.
+	Failed int `json:"failed"`
+	// Interrupted counts the files whose transfer was ongoing when the transfer was cancelled.
+	Interrupted int `json:"interrupted"`
+	// NotStarted counts the files that had been discovered but not processed yet when the transfer was cancelled.
+	NotStarted int `json:"notStarted"`
+	// Ignored counts the local entries that cannot be transferred, such as special files.
+	Ignored int `json:"ignored"`
+	// Files lists the reports of all the processed files, in the order in which they have been completed.
+	Files []*FileReport `json:"files"`
+
+	// onRecord is called each time the outcome of a file is recorded.
+	onRecord func(r *FileReport)
+	mux      sync.Mutex
+}
+
+func (s *TransferSummary) record(r *FileReport, status string, err error) {
+	s.mux.Lock()
+	r.Status = status
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if !r.start.IsZero() {
+		r.Duration = time.Since(r.start).Seconds()
+	}
+	switch status {
+	case StatusTransferred:
+		s.Transferred++
+	case StatusSkipped:
+		s.Skipped++
+	case StatusFailed:
+		s.Failed++
+	case StatusInterrupted:
+		s.Interrupted++
+	case StatusNotStarted:
+		s.NotStarted++
+	case StatusIgnored:
+		s.Ignored++
+	}
+	s.Files = append(s.Files, r)
+	onRecord := s.onRecord
+	s.mux.Unlock()
+	if onRecord != nil {
+		onRecord(r)
+	}
+}
+
+func (s *TransferSummary) transferred(r *FileReport) {
+	s.record(r, StatusTransferred, nil)
+}
+
+func (s *TransferSummary) skipped(r *FileReport) {
+	s.record(r, StatusSkipped, nil)
+}
+
+func (s *TransferSummary) failed(r *FileReport, err error, interrupted bool) {
+	if interrupted {
+		s.record(r, StatusInterrupted, err)
+	} else {
+		s.record(r, StatusFailed, err)
+	}
+}
+
+func (s *TransferSummary) notStarted(r *FileReport) {
+	s.record(r, StatusNotStarted, nil)
+}
+
+func (s *TransferSummary) ignored(rr ...*FileReport) {
+	for _, r := range rr {
+		s.record(r, StatusIgnored, nil)
+	}
+}
+
+// Unfinished returns the files of the passed list that have been neither transferred nor skipped.
+// All files are returned if the summary is nil.
+func (s *TransferSummary) Unfinished(nn []*CrawlNode) []*CrawlNode {
+	done := make(map[string]bool)
+	if s != nil {
+		s.mux.Lock()
+		for _, f := range s.Files {
+			if f.Status == StatusTransferred || f.Status == StatusSkipped {
+				done[f.Source] = true
+			}
+		}
+		s.mux.Unlock()
+	}
+	var res []*CrawlNode
+	for _, n := range nn {
+		if !n.IsDir && !done[n.FullPath] {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// ExitCode returns 0 when all files have been transferred or skipped, 1 when there were files to transfer
+// but none of them could be transferred, and 2 for a partial failure.
+func (s *TransferSummary) ExitCode() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.Failed+s.Interrupted+s.NotStarted == 0 {
+		return 0
+	} else if s.Transferred+s.Skipped == 0 {
+		return 1
+	}
+	return 2
+}
+
+// String renders a human readable report of what was and was not transferred.
+func (s *TransferSummary) String() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d file(s) transferred, %d skipped, %d failed, %d interrupted", s.Transferred, s.Skipped, s.Failed, s.Interrupted)
+	if s.NotStarted > 0 {
+		fmt.Fprintf(&b, ", %d not started", s.NotStarted)
+	}
+	if s.Ignored > 0 {
+		fmt.Fprintf(&b, ", %d ignored", s.Ignored)
+	}
+	b.WriteString("\n")
+	for _, status := range []string{StatusFailed, StatusInterrupted, StatusIgnored} {
+		for _, f := range s.Files {
+			if f.Status != status {
+				continue
+			}
+			if status == StatusIgnored {
+				fmt.Fprintf(&b, " - %s: %s (%s)\n", status, f.Source, f.Error)
+			} else {
+				fmt.Fprintf(&b, " - %s: %s\n", status, f.Source)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SymlinkPolicy defines how symbolic links are handled when walking a local tree.
+type SymlinkPolicy string
+
+const (
+	// SymlinksFollow transfers the files and folders that links point to, as if they were at the link location.
+	SymlinksFollow SymlinkPolicy = "follow"
+	// SymlinksSkip ignores links, they are listed in the transfer summary.
+	SymlinksSkip SymlinkPolicy = "skip"
+	// SymlinksError stops the walk with an error at the first link.
+	SymlinksError SymlinkPolicy = "error"
+)
+
+// Symlinks is the policy applied to the symbolic links that are found while walking a local tree.
+var Symlinks = SymlinksFollow
+
+// ParseSymlinkPolicy validates the policy passed on the command line.
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch p := SymlinkPolicy(strings.ToLower(s)); p {
+	case SymlinksFollow, SymlinksSkip, SymlinksError:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown symlink policy %s, use one of skip, follow or error", s)
+}
+
+// walkLocal walks the local tree like filepath.Walk, applying the Filter and the Symlinks policy. A followed link
+// to a folder is not walked if the folder is one of its ancestors, as it would loop forever. Special files
+// such as sockets, named pipes or devices cannot be transferred: they are skipped with a warning.
+func (c *CrawlNode) walkLocal(ctx context.Context, p string, info os.FileInfo, ancestors []os.FileInfo, callback func(n *CrawlNode) error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	isLink := info.Mode()&os.ModeSymlink != 0
+	if isLink && Symlinks == SymlinksFollow {
+		// Broken links stay links and are ignored below
+		if target, e := os.Stat(p); e == nil {
+			info = target
+		}
+	}
+	relPath := strings.TrimPrefix(p, c.FullPath)
+	if relPath != "" && c.Filter.Excluded(filepath.ToSlash(relPath), info.IsDir()) {
+		return nil
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		if Symlinks == SymlinksError {
+			return fmt.Errorf("%s is a symbolic link, use --symlinks=follow or --symlinks=skip to transfer this folder", p)
+		} else if Symlinks == SymlinksFollow {
+			c.ignore(p, "broken symbolic link")
+		} else {
+			c.ignore(p, "symbolic link")
+		}
+		return nil
+	case !info.IsDir() && !info.Mode().IsRegular():
+		c.ignore(p, "special file")
+		return nil
+	case isLink && info.IsDir():
+		for _, a := range ancestors {
+			if os.SameFile(a, info) {
+				c.ignore(p, "symbolic link loop")
+				return nil
+			}
+		}
+	}
+
+	n := NewLocalNode(p, info)
+	n.RelPath = relPath
+	if e := callback(n); e != nil || !info.IsDir() {
+		return e
+	}
+	names, e := readDirNames(p)
+	if e != nil {
+		return e
+	}
+	ancestors = append(ancestors, info)
+	for _, name := range names {
+		child := filepath.Join(p, name)
+		ci, e := os.Lstat(child)
+		if e != nil {
+			return e
+		}
+		if e := c.walkLocal(ctx, child, ci, ancestors, callback); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// ignore records an entry that cannot be transferred and warns the user, once even if the tree is walked several times.
+func (c *CrawlNode) ignore(p, reason string) {
+	for _, i := range c.Ignored {
+		if i.Source == p {
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Warning: skipping %s (%s)\n", p, reason)
+	c.Ignored = append(c.Ignored, &FileReport{Source: p, Error: reason})
+}
+
+// readDirNames returns the sorted names of the entries of a folder, as filepath.Walk does.
+func readDirNames(dir string) ([]string, error) {
+	f, e := os.Open(dir)
+	if e != nil {
+		return nil, e
+	}
+	names, e := f.Readdirnames(-1)
+	f.Close()
+	if e != nil {
+		return nil, e
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,206 @@
+package rest
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var plainMD5 = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// SyncDiff lists the operations required to mirror a source tree into a target tree.
+type SyncDiff struct {
+	// ToTransfer contains source nodes that are missing or have changed on the target side.
+	ToTransfer []*CrawlNode
+	// ToDelete contains target nodes that do not exist in the source tree anymore.
+	ToDelete []*CrawlNode
+}
+
+// ComputeSyncDiff walks both source and target trees and compares them using size, modification time
+// and, when both sizes are equal but the source is newer, the MD5 ETag of the remote node.
+func ComputeSyncDiff(ctx context.Context, source, target *CrawlNode) (*SyncDiff, error) {
+	sourceNodes, e := source.Walk(ctx)
+	if e != nil {
+		return nil, e
+	}
+	// Ignored files on the target side must be neither compared nor deleted
+	target.Filter = source.Filter
+	targetNodes, e := target.existingChildren(ctx)
+	if e != nil {
+		return nil, e
+	}
+
+	diff := &SyncDiff{}
+	if targetNodes == nil {
+		// Target root does not exist yet: it will be created by MkdirAll
+		diff.ToTransfer = append(diff.ToTransfer, source)
+	}
+	targets := make(map[string]*CrawlNode, len(targetNodes))
+	for _, t := range targetNodes {
+		if t.RelPath != "" {
+			targets[syncKey(t)] = t
+		}
+	}
+	sources := make(map[string]*CrawlNode, len(sourceNodes))
+	for _, s := range sourceNodes {
+		if s.RelPath == "" {
+			continue
+		}
+		key := syncKey(s)
+		sources[key] = s
+		if t, ok := targets[key]; !ok || hasChanged(ctx, s, t) {
+			diff.ToTransfer = append(diff.ToTransfer, s)
+		}
+	}
+
+	var extraneous []string
+	for key := range targets {
+		if _, ok := sources[key]; !ok {
+			extraneous = append(extraneous, key)
+		}
+	}
+	// Only keep the top-most folders, their children are removed with them
+	sort.Strings(extraneous)
+	var lastFolder string
+	for _, key := range extraneous {
+		if lastFolder != "" && strings.HasPrefix(key, lastFolder+"/") {
+			continue
+		}
+		t := targets[key]
+		if t.IsDir {
+			lastFolder = key
+		}
+		diff.ToDelete = append(diff.ToDelete, t)
+	}
+
+	return diff, nil
+}
+
+// DeleteAll removes the passed nodes from the local file system or from the server,
+// in which case they are moved to the recycle bin.
+func (c *CrawlNode) DeleteAll(ctx context.Context, dd []*CrawlNode) error {
+	if len(dd) == 0 {
+		return nil
+	}
+	if c.IsLocal {
+		for _, d := range dd {
+			if DryRun {
+				fmt.Println("Delete: \t", d.FullPath)
+				continue
+			}
+			if e := os.RemoveAll(d.FullPath); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	var paths []string
+	for _, d := range dd {
+		if DryRun {
+			fmt.Println("Delete: \t", d.FullPath)
+			continue
+		}
+		paths = append(paths, d.FullPath)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	jobs, e := DeleteNode(ctx, paths)
+	if e != nil {
+		return e
+	}
+	for _, j := range jobs {
+		if e := MonitorJob(ctx, j); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// existingChildren walks the tree under this target node and returns nil if it does not exist yet.
+func (c *CrawlNode) existingChildren(ctx context.Context) ([]*CrawlNode, error) {
+	var root *CrawlNode
+	if c.IsLocal {
+		i, e := os.Stat(c.FullPath)
+		if e != nil {
+			return nil, nil
+		}
+		root = NewLocalNode(c.FullPath, i)
+	} else {
+		n, ok := StatNode(ctx, c.FullPath)
+		if !ok {
+			return nil, nil
+		}
+		root = NewRemoteNode(n)
+	}
+	if !root.IsDir {
+		return nil, fmt.Errorf("%s exists and is not a folder, cannot synchronize to it", c.FullPath)
+	}
+	root.Filter = c.Filter
+	nn, e := root.Walk(ctx)
+	if nn == nil {
+		nn = []*CrawlNode{}
+	}
+	return nn, e
+}
+
+// syncKey returns a relative path that can be compared between local and remote nodes.
+func syncKey(n *CrawlNode) string {
+	if n.IsLocal {
+		return filepath.ToSlash(n.RelPath)
+	}
+	return n.RelPath
+}
+
+// hasChanged checks if the source node must be transferred again to replace the target node.
+func hasChanged(ctx context.Context, source, target *CrawlNode) bool {
+	if source.IsDir || target.IsDir {
+		return source.IsDir != target.IsDir
+	}
+	if !sameSize(ctx, source, target) {
+		return true
+	}
+	if !source.MTime.After(target.MTime) {
+		return false
+	}
+	// The remote file may have been uploaded with the original modification time of the local file in its metadata
+	if !source.originalMTime(ctx).After(target.originalMTime(ctx)) {
+		return false
+	}
+	// Same size but source is newer: rely on the content hash if it is available
+	local, remote := source, target
+	if !source.IsLocal {
+		local, remote = target, source
+	}
+	if h, e := remote.objectInfo(ctx); e == nil && isEncrypted(h.Metadata) || !plainMD5.MatchString(remote.Etag) {
+		return true
+	}
+	h, e := localMD5(local.FullPath)
+	return e != nil || h != remote.Etag
+}
+
+// sameSize compares the sizes of the contents of a local and a remote file, the metadata of the remote file
+// are only retrieved when the sizes of the nodes differ, as it may have been encrypted.
+func sameSize(ctx context.Context, a, b *CrawlNode) bool {
+	return a.Size == b.Size || a.contentSize(ctx) == b.contentSize(ctx)
+}
+
+func localMD5(p string) (string, error) {
+	f, e := os.Open(p)
+	if e != nil {
+		return "", e
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, e := io.Copy(h, f); e != nil {
+		return "", e
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
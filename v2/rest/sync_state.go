@@ -0,0 +1,361 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pydio/cells-sdk-go/models"
+)
+
+// SyncStateEntry stores what we know about one side of a node at the end of the last synchronization.
+type SyncStateEntry struct {
+	IsDir bool
+	Size  int64
+	MTime int64
+	ETag  string `json:",omitempty"`
+}
+
+// SyncStatePair stores the last synchronized state of a node on both sides.
+type SyncStatePair struct {
+	Local  *SyncStateEntry
+	Remote *SyncStateEntry
+}
+
+// SyncState is the snapshot of a bidirectional synchronization, persisted under the config directory.
+type SyncState struct {
+	Local  string
+	Remote string
+	Nodes  map[string]*SyncStatePair
+
+	file string
+}
+
+// BidirectionalDiff lists the operations required to reconcile a local and a remote folder.
+type BidirectionalDiff struct {
+	Push         []*CrawlNode
+	Pull         []*CrawlNode
+	DeleteLocal  []*CrawlNode
+	DeleteRemote []*CrawlNode
+	// Conflicts lists the local files that must be renamed into conflict copies before the transfers.
+	Conflicts []*SyncConflict
+}
+
+// SyncConflict is a local file that has been modified on both sides and its conflict copy, that is pushed to the server.
+type SyncConflict struct {
+	Original *CrawlNode
+	Copy     *CrawlNode
+}
+
+// LoadSyncState retrieves the snapshot of the last synchronization between these two folders, or an empty one.
+func LoadSyncState(ctx context.Context, localPath, remotePath string) (*SyncState, error) {
+	h := sha1.Sum([]byte(configFrom(ctx).Url + "::" + remotePath + "::" + localPath))
+	s := &SyncState{
+		Local:  localPath,
+		Remote: remotePath,
+		Nodes:  make(map[string]*SyncStatePair),
+		file:   filepath.Join(filepath.Dir(GetConfigFilePath()), "sync", fmt.Sprintf("%x.json", h)),
+	}
+	data, e := ioutil.ReadFile(s.file)
+	if os.IsNotExist(e) {
+		return s, nil
+	} else if e != nil {
+		return nil, e
+	}
+	if e := json.Unmarshal(data, s); e != nil {
+		return nil, fmt.Errorf("could not read synchronization state at %s: %s", s.file, e.Error())
+	}
+	if s.Nodes == nil {
+		s.Nodes = make(map[string]*SyncStatePair)
+	}
+	return s, nil
+}
+
+// Update walks both folders once the synchronization is done and records the nodes that are in sync.
+// Nodes that are only present on one side are not recorded so that they are handled again on next run.
+// The failed nodes, that could not be transferred or deleted, and their children keep their previous state,
+// so that the changes that have not been propagated are detected again on next run.
+func (s *SyncState) Update(ctx context.Context, local, remote *CrawlNode, failed []*CrawlNode) error {
+	ll, e := local.Walk(ctx)
+	if e != nil {
+		return e
+	}
+	rr, e := remote.Walk(ctx)
+	if e != nil {
+		return e
+	}
+	failedKeys := make(map[string]bool, len(failed))
+	for _, f := range failed {
+		failedKeys[syncKey(f)] = true
+	}
+	previous := s.Nodes
+	s.Nodes = make(map[string]*SyncStatePair)
+	for key, prev := range previous {
+		if hasFailed(key, failedKeys) {
+			s.Nodes[key] = prev
+		}
+	}
+	remotes := indexBySyncKey(rr)
+	for key, l := range indexBySyncKey(ll) {
+		r, ok := remotes[key]
+		if !ok || hasFailed(key, failedKeys) || l.IsDir != r.IsDir || (!l.IsDir && !sameSize(ctx, l, r)) {
+			continue
+		}
+		s.Nodes[key] = &SyncStatePair{Local: newSyncStateEntry(l), Remote: newSyncStateEntry(r)}
+	}
+	return s.save()
+}
+
+// hasFailed checks if the node or one of its ancestors is in the failed keys.
+func hasFailed(key string, failed map[string]bool) bool {
+	for k := key; k != "/" && k != "." && k != ""; k = path.Dir(k) {
+		if failed[k] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SyncState) save() error {
+	if e := os.MkdirAll(filepath.Dir(s.file), 0700); e != nil {
+		return e
+	}
+	data, e := json.Marshal(s)
+	if e != nil {
+		return e
+	}
+	return ioutil.WriteFile(s.file, data, 0600)
+}
+
+// ComputeBidirectionalDiff compares both folders with the last known state: changes that happened on
+// one side only are propagated to the other side, files that have been modified on both sides are
+// handled by renaming the local version into a conflict copy that is then uploaded.
+// Nothing is modified on disk: conflict copies are only created by RenameConflicts.
+func ComputeBidirectionalDiff(ctx context.Context, local, remote *CrawlNode, state *SyncState) (*BidirectionalDiff, error) {
+	ll, e := local.Walk(ctx)
+	if e != nil {
+		return nil, e
+	}
+	rr, e := remote.Walk(ctx)
+	if e != nil {
+		return nil, e
+	}
+	locals, remotes := indexBySyncKey(ll), indexBySyncKey(rr)
+
+	keys := make(map[string]struct{})
+	for k := range locals {
+		keys[k] = struct{}{}
+	}
+	for k := range remotes {
+		keys[k] = struct{}{}
+	}
+	for k := range state.Nodes {
+		keys[k] = struct{}{}
+	}
+
+	diff := &BidirectionalDiff{}
+	for key := range keys {
+		l, r := locals[key], remotes[key]
+		prev := state.Nodes[key]
+		if prev == nil {
+			prev = &SyncStatePair{}
+		}
+		lChanged := stateChanged(l, prev.Local)
+		rChanged := stateChanged(r, prev.Remote)
+
+		switch {
+		case !lChanged && !rChanged:
+		case lChanged && !rChanged:
+			if l != nil {
+				diff.Push = append(diff.Push, l)
+			} else if r != nil {
+				diff.DeleteRemote = append(diff.DeleteRemote, r)
+			}
+		case !lChanged && rChanged:
+			if r != nil {
+				diff.Pull = append(diff.Pull, r)
+			} else if l != nil {
+				diff.DeleteLocal = append(diff.DeleteLocal, l)
+			}
+		default:
+			// Changed on both sides
+			if l == nil && r == nil {
+				continue
+			} else if l == nil {
+				diff.Pull = append(diff.Pull, r)
+			} else if r == nil {
+				diff.Push = append(diff.Push, l)
+			} else if l.IsDir && r.IsDir || sameContent(ctx, l, r) {
+				continue
+			} else if l.IsDir || r.IsDir {
+				return nil, fmt.Errorf("%s is a file on one side and a folder on the other side, please fix this manually", key)
+			} else {
+				conflict := conflictCopy(local, l)
+				diff.Conflicts = append(diff.Conflicts, &SyncConflict{Original: l, Copy: conflict})
+				diff.Push = append(diff.Push, conflict)
+				diff.Pull = append(diff.Pull, r)
+			}
+		}
+	}
+
+	// Make sure parent folders of transferred nodes are created and not deleted on the target side
+	diff.Push, diff.DeleteRemote = withParents(diff.Push, locals, remotes, diff.DeleteRemote)
+	diff.Pull, diff.DeleteLocal = withParents(diff.Pull, remotes, locals, diff.DeleteLocal)
+	diff.DeleteLocal = topMostNodes(diff.DeleteLocal)
+	diff.DeleteRemote = topMostNodes(diff.DeleteRemote)
+
+	// Folders must be created before their children
+	for _, nn := range [][]*CrawlNode{diff.Push, diff.Pull} {
+		sort.Slice(nn, func(i, j int) bool { return syncKey(nn[i]) < syncKey(nn[j]) })
+	}
+	return diff, nil
+}
+
+func newSyncStateEntry(n *CrawlNode) *SyncStateEntry {
+	e := &SyncStateEntry{IsDir: n.IsDir}
+	if !n.IsDir {
+		e.Size = n.Size
+		e.MTime = n.MTime.Unix()
+		if !n.IsLocal {
+			e.ETag = n.Etag
+		}
+	}
+	return e
+}
+
+// stateChanged checks if a node has been created, modified or deleted since the last synchronization.
+func stateChanged(n *CrawlNode, previous *SyncStateEntry) bool {
+	if n == nil || previous == nil {
+		return n != nil || previous != nil
+	}
+	if n.IsDir || previous.IsDir {
+		return n.IsDir != previous.IsDir
+	}
+	if n.Size != previous.Size || n.MTime.Unix() != previous.MTime {
+		return true
+	}
+	return !n.IsLocal && previous.ETag != "" && n.Etag != previous.ETag
+}
+
+// sameContent checks if a local and a remote file are identical. When the remote ETag is not
+// a plain MD5 hash (e.g. for multipart uploads) or the remote file is encrypted, we can only rely on the size.
+func sameContent(ctx context.Context, l, r *CrawlNode) bool {
+	if l.IsDir || r.IsDir || !sameSize(ctx, l, r) {
+		return false
+	}
+	if h, e := r.objectInfo(ctx); e == nil && isEncrypted(h.Metadata) || !plainMD5.MatchString(r.Etag) {
+		return true
+	}
+	h, e := localMD5(l.FullPath)
+	return e == nil && h == r.Etag
+}
+
+// RenameConflicts renames the local files that have been modified on both sides into their conflict copies,
+// so that both versions are kept. It must be called before the transfers.
+func (d *BidirectionalDiff) RenameConflicts() error {
+	for _, c := range d.Conflicts {
+		if DryRun {
+			fmt.Println("Conflict: \t", c.Original.FullPath, "=>", c.Copy.FullPath)
+		} else if e := os.Rename(c.Original.FullPath, c.Copy.FullPath); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// conflictCopy computes the node of the conflict copy of a local file, next to the original file.
+func conflictCopy(root, n *CrawlNode) *CrawlNode {
+	ext := filepath.Ext(n.FullPath)
+	conflictPath := fmt.Sprintf("%s-conflict-%s%s", strings.TrimSuffix(n.FullPath, ext), time.Now().Format("20060102-150405"), ext)
+	c := NewLocalNode(conflictPath, n.FileInfo)
+	c.RelPath = strings.TrimPrefix(conflictPath, root.FullPath)
+	return c
+}
+
+// withParents adds the missing ancestor folders of the transferred nodes and removes them from the nodes to be deleted.
+func withParents(transfers []*CrawlNode, sources, targets map[string]*CrawlNode, deletes []*CrawlNode) ([]*CrawlNode, []*CrawlNode) {
+	added := make(map[string]bool)
+	for _, t := range transfers {
+		added[syncKey(t)] = true
+	}
+	required := make(map[string]bool)
+	for _, t := range transfers {
+		for p := path.Dir(syncKey(t)); p != "/" && p != "."; p = path.Dir(p) {
+			required[p] = true
+			if _, ok := targets[p]; ok || added[p] {
+				continue
+			}
+			if s, ok := sources[p]; ok {
+				transfers = append(transfers, s)
+				added[p] = true
+			}
+		}
+	}
+	var kept []*CrawlNode
+	for _, d := range deletes {
+		if !required[syncKey(d)] {
+			kept = append(kept, d)
+		}
+	}
+	return transfers, kept
+}
+
+// topMostNodes filters out nodes whose ancestor is already in the list.
+func topMostNodes(nn []*CrawlNode) []*CrawlNode {
+	sort.Slice(nn, func(i, j int) bool { return syncKey(nn[i]) < syncKey(nn[j]) })
+	var res []*CrawlNode
+	var lastFolder string
+	for _, n := range nn {
+		key := syncKey(n)
+		if lastFolder != "" && strings.HasPrefix(key, lastFolder+"/") {
+			continue
+		}
+		if n.IsDir {
+			lastFolder = key
+		}
+		res = append(res, n)
+	}
+	return res
+}
+
+func indexBySyncKey(nn []*CrawlNode) map[string]*CrawlNode {
+	res := make(map[string]*CrawlNode, len(nn))
+	for _, n := range nn {
+		if n.RelPath != "" {
+			res[syncKey(n)] = n
+		}
+	}
+	return res
+}
+
+// EnsureRemoteFolder creates the passed folder on the server if it does not exist yet.
+func EnsureRemoteFolder(ctx context.Context, folder string) (*CrawlNode, error) {
+	if n, ok := StatNode(ctx, folder); ok {
+		if n.Type != models.TreeNodeTypeCOLLECTION {
+			return nil, fmt.Errorf("%s exists on the server and is not a folder", folder)
+		}
+		return NewRemoteNode(n), nil
+	}
+	if e := TreeCreateNodes(ctx, []*models.TreeNode{{Path: folder, Type: models.TreeNodeTypeCOLLECTION}}); e != nil {
+		return nil, e
+	}
+	e := RetryCallback(ctx, func() error {
+		if _, ok := StatNode(ctx, folder); !ok {
+			return fmt.Errorf("cannot stat folder %s just after its creation", folder)
+		}
+		return nil
+	}, 5, 2*time.Second)
+	if e != nil {
+		return nil, e
+	}
+	n, _ := StatNode(ctx, folder)
+	return NewRemoteNode(n), nil
+}
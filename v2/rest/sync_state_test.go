@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pydio/cells-sdk-go/models"
+)
+
+func remoteNode(relPath string, isDir bool) *CrawlNode {
+	return &CrawlNode{RelPath: relPath, FullPath: "root/" + relPath, IsDir: isDir}
+}
+
+func syncKeys(nn []*CrawlNode) []string {
+	var keys []string
+	for _, n := range nn {
+		keys = append(keys, syncKey(n))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestStateChanged(t *testing.T) {
+	mtime := time.Unix(1600000000, 0)
+	file := &CrawlNode{Size: 10, MTime: mtime, TreeNode: models.TreeNode{Etag: "etag"}}
+	tests := []struct {
+		name     string
+		node     *CrawlNode
+		previous *SyncStateEntry
+		changed  bool
+	}{
+		{"unknown", nil, nil, false},
+		{"created", file, nil, true},
+		{"deleted", nil, &SyncStateEntry{Size: 10}, true},
+		{"unchanged", file, &SyncStateEntry{Size: 10, MTime: mtime.Unix(), ETag: "etag"}, false},
+		{"resized", file, &SyncStateEntry{Size: 11, MTime: mtime.Unix()}, true},
+		{"touched", file, &SyncStateEntry{Size: 10, MTime: mtime.Unix() - 1}, true},
+		{"new etag", file, &SyncStateEntry{Size: 10, MTime: mtime.Unix(), ETag: "other"}, true},
+		{"no previous etag", file, &SyncStateEntry{Size: 10, MTime: mtime.Unix()}, false},
+		{"local etag ignored", &CrawlNode{IsLocal: true, Size: 10, MTime: mtime}, &SyncStateEntry{Size: 10, MTime: mtime.Unix(), ETag: "etag"}, false},
+		{"same folder", &CrawlNode{IsDir: true}, &SyncStateEntry{IsDir: true}, false},
+		{"file to folder", &CrawlNode{IsDir: true}, &SyncStateEntry{Size: 10}, true},
+		{"folder to file", file, &SyncStateEntry{IsDir: true}, true},
+	}
+	for _, tt := range tests {
+		if got := stateChanged(tt.node, tt.previous); got != tt.changed {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.changed, got)
+		}
+	}
+}
+
+func TestWithParents(t *testing.T) {
+	a, ab, abc := remoteNode("a", true), remoteNode("a/b", true), remoteNode("a/b/c.txt", false)
+	x, xy := remoteNode("x", true), remoteNode("x/y.txt", false)
+	sources := indexBySyncKey([]*CrawlNode{a, ab, abc, x, xy})
+	tests := []struct {
+		name      string
+		transfers []*CrawlNode
+		targets   []*CrawlNode
+		deletes   []*CrawlNode
+		transfer  []string
+		kept      []string
+	}{
+		{"missing parents", []*CrawlNode{abc}, nil, nil, []string{"a", "a/b", "a/b/c.txt"}, nil},
+		{"existing parents", []*CrawlNode{abc}, []*CrawlNode{remoteNode("a", true), remoteNode("a/b", true)}, nil, []string{"a/b/c.txt"}, nil},
+		{"parent already transferred", []*CrawlNode{ab, abc}, []*CrawlNode{remoteNode("a", true)}, nil, []string{"a/b", "a/b/c.txt"}, nil},
+		{"required parents not deleted", []*CrawlNode{xy}, []*CrawlNode{remoteNode("x", true), remoteNode("z", true)},
+			[]*CrawlNode{remoteNode("x", true), remoteNode("z", true)}, []string{"x/y.txt"}, []string{"z"}},
+	}
+	for _, tt := range tests {
+		transfer, kept := withParents(append([]*CrawlNode{}, tt.transfers...), sources, indexBySyncKey(tt.targets), tt.deletes)
+		if got := syncKeys(transfer); !reflect.DeepEqual(got, tt.transfer) {
+			t.Errorf("%s: expected transfers %v, got %v", tt.name, tt.transfer, got)
+		}
+		if got := syncKeys(kept); !reflect.DeepEqual(got, tt.kept) {
+			t.Errorf("%s: expected deletes %v, got %v", tt.name, tt.kept, got)
+		}
+	}
+}
+
+func TestTopMostNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []*CrawlNode
+		expected []string
+	}{
+		{"empty", nil, nil},
+		{"children of a folder", []*CrawlNode{remoteNode("a/b.txt", false), remoteNode("a", true), remoteNode("a/c", true), remoteNode("a/c/d.txt", false)}, []string{"a"}},
+		{"siblings", []*CrawlNode{remoteNode("a", true), remoteNode("ab", true), remoteNode("ab/c.txt", false)}, []string{"a", "ab"}},
+		{"file prefix", []*CrawlNode{remoteNode("a", false), remoteNode("a/b.txt", false)}, []string{"a", "a/b.txt"}},
+	}
+	for _, tt := range tests {
+		if got := syncKeys(topMostNodes(tt.nodes)); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestHasFailed(t *testing.T) {
+	failed := map[string]bool{"a/b": true, "c.txt": true}
+	tests := []struct {
+		key    string
+		failed bool
+	}{
+		{"a/b", true},
+		{"a/b/c/d.txt", true},
+		{"c.txt", true},
+		{"a", false},
+		{"a/bc", false},
+		{"d/c.txt", false},
+	}
+	for _, tt := range tests {
+		if got := hasFailed(tt.key, failed); got != tt.failed {
+			t.Errorf("%s: expected %v, got %v", tt.key, tt.failed, got)
+		}
+	}
+}
+
+func TestRenameConflicts(t *testing.T) {
+	dir, e := ioutil.TempDir("", "conflicts")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "report.pdf")
+	if e := ioutil.WriteFile(file, []byte("local"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	info, _ := os.Stat(file)
+	root := NewLocalNode(dir, info)
+	original := NewLocalNode(file, info)
+	original.RelPath = "/report.pdf"
+
+	c := conflictCopy(root, original)
+	if _, e := os.Stat(file); e != nil {
+		t.Fatal("computing the conflict copy must not touch the original file")
+	}
+	if filepath.Dir(c.FullPath) != dir || filepath.Ext(c.FullPath) != ".pdf" || c.RelPath != "/"+filepath.Base(c.FullPath) {
+		t.Fatalf("unexpected conflict copy %s (%s)", c.FullPath, c.RelPath)
+	}
+
+	diff := &BidirectionalDiff{Conflicts: []*SyncConflict{{Original: original, Copy: c}}}
+	if e := diff.RenameConflicts(); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := os.Stat(file); !os.IsNotExist(e) {
+		t.Errorf("%s should have been renamed", file)
+	}
+	if data, e := ioutil.ReadFile(c.FullPath); e != nil || string(data) != "local" {
+		t.Errorf("conflict copy should have the local content, got %q (%v)", data, e)
+	}
+}
@@ -0,0 +1,206 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// RateWindow applies a specific bandwidth limit during a daily time window.
+// Windows whose end is before their start span over midnight.
+type RateWindow struct {
+	// Start and End are expressed in minutes since midnight, local time.
+	Start, End int
+	// Rate is the limit in bytes per second, 0 meaning unlimited.
+	Rate int64
+}
+
+func (w RateWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return m >= w.Start && m < w.End
+	}
+	return m >= w.Start || m < w.End
+}
+
+// rateLimiter is a token bucket shared by all the transfers of the process, so that the limit is global
+// whatever the number of files and parts that are transferred in parallel. Readers can go into debt:
+// they then sleep until the bucket is refilled, which keeps the average rate under the limit.
+type rateLimiter struct {
+	rate     int64
+	schedule []RateWindow
+
+	tokens float64
+	last   time.Time
+	mux    sync.Mutex
+}
+
+var limiter = &rateLimiter{}
+
+// SetRateLimit configures the global bandwidth limit in bytes per second, 0 meaning unlimited.
+// When the current time is in one of the schedule windows, the rate of this window applies instead.
+func SetRateLimit(rate int64, schedule []RateWindow) {
+	limiter.mux.Lock()
+	defer limiter.mux.Unlock()
+	limiter.rate = rate
+	limiter.schedule = schedule
+	limiter.tokens = 0
+	limiter.last = time.Time{}
+}
+
+// currentRate must be called with the lock held.
+func (l *rateLimiter) currentRate(now time.Time) int64 {
+	for _, w := range l.schedule {
+		if w.contains(now) {
+			return w.Rate
+		}
+	}
+	return l.rate
+}
+
+// maxRead returns the maximum number of bytes that should be read at once, so that a single read
+// never waits for more than a second. It returns 0 when there is no limit.
+func (l *rateLimiter) maxRead() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return int(l.currentRate(time.Now()))
+}
+
+// wait consumes n tokens and blocks until the bucket is not in debt anymore, or until the context is cancelled.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	l.mux.Lock()
+	now := time.Now()
+	rate := float64(l.currentRate(now))
+	if rate <= 0 {
+		l.mux.Unlock()
+		return nil
+	}
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * rate
+		// Allow a burst of one second at most
+		if l.tokens > rate {
+			l.tokens = rate
+		}
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / rate * float64(time.Second))
+	}
+	l.mux.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttleTransfer is a request option that applies the global bandwidth limit to the body of the request
+// while it is sent and to the body of the response while it is received. The limit is not applied
+// when reading the content to compute its signature, so that only network usage is limited.
+func throttleTransfer(r *request.Request) {
+	r.Handlers.Send.PushFront(func(r *request.Request) {
+		if b := r.HTTPRequest.Body; b != nil && b != http.NoBody {
+			r.HTTPRequest.Body = &throttledReadCloser{ReadCloser: b, ctx: r.Context()}
+		}
+	})
+	r.Handlers.Send.PushBack(func(r *request.Request) {
+		if r.HTTPResponse != nil && r.HTTPResponse.Body != nil {
+			r.HTTPResponse.Body = &throttledReadCloser{ReadCloser: r.HTTPResponse.Body, ctx: r.Context()}
+		}
+	})
+}
+
+type throttledReadCloser struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+// Read limits the size of the buffer according to the current rate, so that a single read never
+// waits for more than a second, then waits for the bandwidth to be available.
+func (r *throttledReadCloser) Read(p []byte) (int, error) {
+	if max := limiter.maxRead(); max > 0 && len(p) > max {
+		p = p[:max]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if e := limiter.wait(r.ctx, n); e != nil {
+		return n, e
+	}
+	return n, err
+}
+
+// ParseRate converts a human readable rate like "500K", "10M" or "1.5G" (per second) to a number of bytes.
+// Units are powers of 1024, a trailing "B" or "/s" is accepted. Zero means unlimited.
+func ParseRate(s string) (int64, error) {
+	v := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "/S")
+	v = strings.TrimSuffix(v, "B")
+	if v == "" {
+		return 0, nil
+	}
+	multiplier := float64(1)
+	switch v[len(v)-1] {
+	case 'K':
+		multiplier = 1024
+	case 'M':
+		multiplier = 1024 * 1024
+	case 'G':
+		multiplier = 1024 * 1024 * 1024
+	}
+	if multiplier > 1 {
+		v = v[:len(v)-1]
+	}
+	f, e := strconv.ParseFloat(v, 64)
+	if e != nil || f < 0 {
+		return 0, fmt.Errorf("invalid rate %s, use for instance 500K or 10M", s)
+	}
+	return int64(f * multiplier), nil
+}
+
+// ParseRateSchedule parses windows in the form "HH:MM-HH:MM=RATE", e.g. "08:00-18:00=1M".
+func ParseRateSchedule(windows []string) ([]RateWindow, error) {
+	var schedule []RateWindow
+	for _, w := range windows {
+		parts := strings.SplitN(w, "=", 2)
+		bounds := strings.SplitN(parts[0], "-", 2)
+		if len(parts) != 2 || len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid schedule %s, use for instance 08:00-18:00=1M", w)
+		}
+		start, e := parseClock(bounds[0])
+		if e != nil {
+			return nil, e
+		}
+		end, e := parseClock(bounds[1])
+		if e != nil {
+			return nil, e
+		}
+		rate, e := ParseRate(parts[1])
+		if e != nil {
+			return nil, e
+		}
+		schedule = append(schedule, RateWindow{Start: start, End: end, Rate: rate})
+	}
+	return schedule, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, e := time.Parse("15:04", strings.TrimSpace(s))
+	if e != nil {
+		return 0, fmt.Errorf("invalid time %s, use the HH:MM format", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate     string
+		expected int64
+		invalid  bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"500", 500, false},
+		{"500K", 500 * 1024, false},
+		{"10m", 10 * 1024 * 1024, false},
+		{"1.5G", 1536 * 1024 * 1024, false},
+		{"10MB", 10 * 1024 * 1024, false},
+		{" 2M/s ", 2 * 1024 * 1024, false},
+		{"M", 0, true},
+		{"-1M", 0, true},
+		{"10T", 0, true},
+		{"fast", 0, true},
+	}
+	for _, tt := range tests {
+		got, e := ParseRate(tt.rate)
+		if tt.invalid {
+			if e == nil {
+				t.Errorf("%q should be invalid", tt.rate)
+			}
+		} else if e != nil || got != tt.expected {
+			t.Errorf("%q: expected %d, got %d (%v)", tt.rate, tt.expected, got, e)
+		}
+	}
+}
+
+func TestParseRateSchedule(t *testing.T) {
+	tests := []struct {
+		windows  []string
+		expected []RateWindow
+		invalid  bool
+	}{
+		{nil, nil, false},
+		{[]string{"08:00-18:00=1M"}, []RateWindow{{Start: 480, End: 1080, Rate: 1024 * 1024}}, false},
+		{[]string{"22:30-06:00=0", "12:00-14:00=500K"}, []RateWindow{{Start: 1350, End: 360}, {Start: 720, End: 840, Rate: 500 * 1024}}, false},
+		{[]string{"08:00-18:00"}, nil, true},
+		{[]string{"08:00=1M"}, nil, true},
+		{[]string{"8h-18h=1M"}, nil, true},
+		{[]string{"08:00-25:00=1M"}, nil, true},
+		{[]string{"08:00-18:00=fast"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, e := ParseRateSchedule(tt.windows)
+		if tt.invalid {
+			if e == nil {
+				t.Errorf("%v should be invalid", tt.windows)
+			}
+		} else if e != nil || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%v: expected %v, got %v (%v)", tt.windows, tt.expected, got, e)
+		}
+	}
+}
+
+func TestRateWindowContains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2021, 2, 18, hour, minute, 0, 0, time.Local)
+	}
+	day := RateWindow{Start: 480, End: 1080}
+	night := RateWindow{Start: 1350, End: 360}
+	tests := []struct {
+		window   RateWindow
+		time     time.Time
+		contains bool
+	}{
+		{day, at(8, 0), true},
+		{day, at(17, 59), true},
+		{day, at(18, 0), false},
+		{day, at(7, 59), false},
+		{night, at(23, 0), true},
+		{night, at(2, 0), true},
+		{night, at(6, 0), false},
+		{night, at(12, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.window.contains(tt.time); got != tt.contains {
+			t.Errorf("%v at %s: expected %v, got %v", tt.window, tt.time.Format("15:04"), tt.contains, got)
+		}
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	l := &rateLimiter{rate: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	// Consuming 1000 bytes at 1 byte per second would block for about 16 minutes
+	if e := l.wait(ctx, 1000); e != context.Canceled {
+		t.Errorf("expected the wait to be cancelled, got %v", e)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("cancelled wait returned after %s", d)
+	}
+	if e := (&rateLimiter{}).wait(context.Background(), 1000); e != nil {
+		t.Errorf("unlimited wait should not fail, got %v", e)
+	}
+}
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"fmt"
+)
+
+// Limits imposed by the S3 API on multipart uploads.
+const (
+	MinPartSize   int64 = 5 * 1024 * 1024
+	MaxPartSize   int64 = 5 * 1024 * 1024 * 1024
+	MaxPartsCount int64 = 10000
+	MaxObjectSize int64 = 5 * 1024 * 1024 * 1024 * 1024
+)
+
+var (
+	// MultipartThreshold is the size from which files are uploaded by parts.
+	MultipartThreshold int64 = 100 * 1024 * 1024
+	// PartSize is the size of the parts of multipart transfers. It is increased for files
+	// that would otherwise have more than MaxPartsCount parts.
+	PartSize int64 = 50 * 1024 * 1024
+	// PartsConcurrency is the number of parts that are transferred in parallel for multipart uploads and downloads.
+	PartsConcurrency = 3
+)
+
+// TransferSettings tune the transfers, they can be defined in the "transfers" section of the config file.
+// Zero values keep the defaults, sizes are expressed in MB.
+type TransferSettings struct {
+	ParallelFiles      int   `json:"parallelFiles,omitempty"`
+	MultipartThreshold int64 `json:"multipartThreshold,omitempty"`
+	PartSize           int64 `json:"partSize,omitempty"`
+	PartsConcurrency   int   `json:"partsConcurrency,omitempty"`
+}
+
+// ApplyTransferSettings validates the settings against the S3 limits and applies the non-zero ones.
+func ApplyTransferSettings(s TransferSettings) error {
+	const mb = 1024 * 1024
+	if s.ParallelFiles < 0 || s.PartsConcurrency < 0 || s.MultipartThreshold < 0 || s.PartSize < 0 {
+		return fmt.Errorf("transfer settings must be positive numbers")
+	}
+	if s.PartSize > 0 && (s.PartSize*mb < MinPartSize || s.PartSize*mb > MaxPartSize) {
+		return fmt.Errorf("part size must be between %dMB and %dMB", MinPartSize/mb, MaxPartSize/mb)
+	}
+	if s.MultipartThreshold*mb > MaxPartSize {
+		// Files are sent with a single request below the threshold
+		return fmt.Errorf("multipart threshold cannot exceed %dMB", MaxPartSize/mb)
+	}
+	if s.ParallelFiles > 0 {
+		QueueSize = s.ParallelFiles
+	}
+	if s.MultipartThreshold > 0 {
+		MultipartThreshold = s.MultipartThreshold * mb
+	}
+	if s.PartSize > 0 {
+		PartSize = s.PartSize * mb
+	}
+	if s.PartsConcurrency > 0 {
+		PartsConcurrency = s.PartsConcurrency
+	}
+	return nil
+}
+
+// partSizeFor returns the size of the parts for a file of the given size: PartSize, or the smallest
+// round number of MB that keeps the number of parts under MaxPartsCount for very big files.
+func partSizeFor(size int64) int64 {
+	const mb = 1024 * 1024
+	if size <= PartSize*MaxPartsCount {
+		return PartSize
+	}
+	min := (size + MaxPartsCount - 1) / MaxPartsCount
+	return (min + mb - 1) / mb * mb
+}
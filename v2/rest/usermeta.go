@@ -0,0 +1,192 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pydio/cells-sdk-go/client/user_meta_service"
+	"github.com/pydio/cells-sdk-go/models"
+)
+
+// UserMetaPrefix starts the names of the namespaces of user metadata that are defined on the server.
+const UserMetaPrefix = "usermeta-"
+
+// DefaultTagsNamespace is the namespace used for tags when none is specified.
+const DefaultTagsNamespace = UserMetaPrefix + "tags"
+
+// UserMeta holds the user metadata attached to the uploaded nodes, it is nil when there is nothing to attach.
+var UserMeta *UploadMeta
+
+// UploadMeta defines the values of user metadata for uploaded nodes, by namespace.
+type UploadMeta struct {
+	// All is applied to every uploaded node, values are JSON encoded.
+	All map[string]string
+	// ByPath is applied to the nodes whose path, relative to the source of the transfer (or the archive
+	// that is extracted), is the key. Its values take precedence over the ones of All.
+	ByPath map[string]map[string]string
+
+	tagsNamespace string
+}
+
+// NewUploadMeta parses "key=value" pairs and tags that apply to all nodes, as well as a sidecar JSON file that maps
+// the relative paths of files to objects of metadata, e.g. {"docs/report.pdf": {"classification": "internal", "tags": ["finance", "2021"]}}.
+// Keys are namespaces, the usermeta- prefix can be omitted and "tags" designates the tags namespace.
+// It returns nil when no metadata is defined.
+func NewUploadMeta(pairs, tags []string, tagsNamespace, sidecar string) (*UploadMeta, error) {
+	if tagsNamespace == "" {
+		tagsNamespace = DefaultTagsNamespace
+	}
+	m := &UploadMeta{All: map[string]string{}, ByPath: map[string]map[string]string{}, tagsNamespace: namespace(tagsNamespace)}
+	for _, p := range pairs {
+		parts := strings.SplitN(p, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid metadata %s, use key=value", p)
+		}
+		m.All[m.namespace(parts[0])] = jsonString(parts[1])
+	}
+	if t := joinTags(tags); t != "" {
+		m.All[m.tagsNamespace] = jsonString(t)
+	}
+	if sidecar != "" {
+		data, e := ioutil.ReadFile(sidecar)
+		if e != nil {
+			return nil, e
+		}
+		raw := map[string]map[string]interface{}{}
+		if e := json.Unmarshal(data, &raw); e != nil {
+			return nil, fmt.Errorf("invalid metadata file %s: %s", sidecar, e.Error())
+		}
+		for p, values := range raw {
+			meta := map[string]string{}
+			for k, v := range values {
+				s, e := metaValue(v)
+				if e != nil {
+					return nil, fmt.Errorf("invalid metadata %s for %s in %s: %s", k, p, sidecar, e.Error())
+				}
+				meta[m.namespace(k)] = s
+			}
+			m.ByPath[normalizeMetaPath(p)] = meta
+		}
+	}
+	if len(m.All) == 0 && len(m.ByPath) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// namespace converts a key to the name of a user metadata namespace.
+func (m *UploadMeta) namespace(key string) string {
+	key = strings.TrimSpace(key)
+	if key == "tags" {
+		return m.tagsNamespace
+	}
+	return namespace(key)
+}
+
+func namespace(key string) string {
+	if strings.HasPrefix(key, UserMetaPrefix) {
+		return key
+	}
+	return UserMetaPrefix + key
+}
+
+// metaValue encodes a value of the sidecar file: lists are rendered as comma separated tags.
+func metaValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return jsonString(t), nil
+	case []interface{}:
+		var tags []string
+		for _, i := range t {
+			s, ok := i.(string)
+			if !ok {
+				return "", fmt.Errorf("lists can only contain strings")
+			}
+			tags = append(tags, s)
+		}
+		return jsonString(joinTags(tags)), nil
+	case nil:
+		return "", fmt.Errorf("value cannot be null")
+	default:
+		data, e := json.Marshal(t)
+		return string(data), e
+	}
+}
+
+// joinTags renders tags the way they are stored by the server, as a comma separated list.
+func joinTags(tags []string) string {
+	var tt []string
+	for _, t := range tags {
+		for _, s := range strings.Split(t, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				tt = append(tt, s)
+			}
+		}
+	}
+	return strings.Join(tt, ",")
+}
+
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func normalizeMetaPath(p string) string {
+	return strings.Trim(filepath.ToSlash(p), "/")
+}
+
+// forPath returns the metadata to attach to the node uploaded from the passed relative path.
+func (m *UploadMeta) forPath(relPath string) map[string]string {
+	meta := make(map[string]string, len(m.All))
+	for k, v := range m.All {
+		meta[k] = v
+	}
+	for k, v := range m.ByPath[normalizeMetaPath(relPath)] {
+		meta[k] = v
+	}
+	return meta
+}
+
+// apply attaches the metadata to the node that has just been uploaded at remotePath, once it has been indexed.
+// It does nothing if no metadata is defined.
+func (m *UploadMeta) apply(ctx context.Context, relPath, remotePath string) error {
+	if m == nil {
+		return nil
+	}
+	meta := m.forPath(relPath)
+	if len(meta) == 0 {
+		return nil
+	}
+	var uuid string
+	e := RetryCallback(ctx, func() error {
+		n, ok := StatNode(ctx, remotePath)
+		if !ok || n.UUID == "" {
+			return fmt.Errorf("cannot stat %s to set its metadata, it is not indexed yet", remotePath)
+		}
+		uuid = n.UUID
+		return nil
+	}, 5, 2*time.Second)
+	if e != nil {
+		return e
+	}
+
+	client, e := getApiClient(ctx)
+	if e != nil {
+		return e
+	}
+	var mm []*models.IdmUserMeta
+	for ns, v := range meta {
+		mm = append(mm, &models.IdmUserMeta{NodeUUID: uuid, Namespace: ns, JSONValue: v})
+	}
+	params := user_meta_service.NewUpdateUserMetaParamsWithContext(ctx)
+	params.Body = &models.IdmUpdateUserMetaRequest{MetaDatas: mm, Operation: models.UpdateUserMetaRequestUserMetaOpPUT}
+	if _, e := client.UserMetaService.UpdateUserMeta(params); e != nil {
+		return fmt.Errorf("%s has been uploaded but its metadata could not be set: %s", remotePath, e.Error())
+	}
+	return nil
+}
@@ -1,12 +1,13 @@
 package rest
 
 import (
+	"context"
 	"time"
 )
 
 // RetryCallback implements boiler plate code to easily call the same function until it suceeds
-// or a time-out is reached.
-func RetryCallback(callback func() error, number int, interval time.Duration) error {
+// or a time-out is reached. It stops waiting as soon as the context is cancelled.
+func RetryCallback(ctx context.Context, callback func() error, number int, interval time.Duration) error {
 
 	var e error
 	for i := 0; i < number; i++ {
@@ -14,7 +15,11 @@ func RetryCallback(callback func() error, number int, interval time.Duration) er
 			break
 		}
 		if i < number-1 {
-			<-time.After(interval)
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 	}
 
@@ -0,0 +1,200 @@
+package rest
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var (
+	// VerifyTransfers enables the comparison of the checksums of transferred files with the ones known by the server.
+	VerifyTransfers bool
+	// VerifyRetries is the number of times a transfer is started again when checksums do not match.
+	VerifyRetries = 2
+)
+
+var (
+	// errCannotVerify is returned when the server does not expose a checksum that we can compare with.
+	errCannotVerify = errors.New("checksum could not be verified")
+	// errChecksumMismatch is wrapped by the errors returned when the content differs from the remote one.
+	errChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// checksums computes the hashes of a content while it is streamed. When partSize is set,
+// it also computes the MD5 of each part, to be compared with the ETag of a multipart object.
+type checksums struct {
+	md5      hash.Hash
+	sha256   hash.Hash
+	partSize int64
+	parts    [][]byte
+	partHash hash.Hash
+	partLen  int64
+	// complete is false if some bytes could not be hashed while streaming
+	complete bool
+	// partsGuessed is set when the part size of the remote object is unknown: a different multipart ETag
+	// then does not prove that the content differs, as the object may have been uploaded with other parts.
+	partsGuessed bool
+}
+
+func newChecksums(partSize int64) *checksums {
+	return &checksums{
+		md5:      md5.New(),
+		sha256:   sha256.New(),
+		partSize: partSize,
+		partHash: md5.New(),
+		complete: true,
+	}
+}
+
+// newDownloadChecksums prepares the checksums of a downloaded file. Parts are hashed with the part size
+// that this client uses for uploads, which may not be the one of the uploader.
+func newDownloadChecksums(size int64) *checksums {
+	s := newChecksums(partSizeFor(size))
+	s.partsGuessed = true
+	return s
+}
+
+func (s *checksums) Write(p []byte) (int, error) {
+	s.md5.Write(p)
+	s.sha256.Write(p)
+	if s.partSize > 0 {
+		for rest := p; len(rest) > 0; {
+			n := s.partSize - s.partLen
+			if int64(len(rest)) < n {
+				n = int64(len(rest))
+			}
+			s.partHash.Write(rest[:n])
+			s.partLen += n
+			rest = rest[n:]
+			if s.partLen == s.partSize {
+				s.closePart()
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (s *checksums) closePart() {
+	s.parts = append(s.parts, s.partHash.Sum(nil))
+	s.partHash = md5.New()
+	s.partLen = 0
+}
+
+// verify compares the local checksums with the remote ETag, that is either the MD5 of the content or,
+// for multipart objects, the MD5 of the concatenated MD5 of each part followed by the number of parts.
+// When the server exposes a SHA-256 hash of the content, it is also checked.
+func (s *checksums) verify(remoteETag, remoteSHA256 string) error {
+	if !s.complete {
+		return errCannotVerify
+	}
+	if s.partLen > 0 {
+		s.closePart()
+	}
+	// Once the SHA-256 hash has been compared, the content is verified even if the ETag cannot be
+	unverified := errCannotVerify
+	if remoteSHA256 != "" {
+		if local := fmt.Sprintf("%x", s.sha256.Sum(nil)); local != strings.ToLower(remoteSHA256) {
+			return fmt.Errorf("%w: local SHA-256 is %s, remote is %s", errChecksumMismatch, local, remoteSHA256)
+		}
+		unverified = nil
+	}
+
+	eTag := strings.Trim(remoteETag, "\"")
+	var local string
+	if i := strings.LastIndex(eTag, "-"); i > 0 {
+		n, e := strconv.Atoi(eTag[i+1:])
+		if e != nil || n != len(s.parts) {
+			// Object has been uploaded with another part size
+			return unverified
+		}
+		h := md5.New()
+		for _, p := range s.parts {
+			h.Write(p)
+		}
+		local = fmt.Sprintf("%x-%d", h.Sum(nil), len(s.parts))
+		if local != eTag && s.partsGuessed {
+			return unverified
+		}
+	} else if plainMD5.MatchString(eTag) {
+		local = fmt.Sprintf("%x", s.md5.Sum(nil))
+	} else {
+		return unverified
+	}
+	if local != eTag {
+		return fmt.Errorf("%w: local is %s, remote ETag is %s", errChecksumMismatch, local, eTag)
+	}
+	return nil
+}
+
+// remoteChecksums retrieves the ETag of a remote file and its SHA-256 hash if the server exposes one.
+func remoteChecksums(ctx context.Context, pathToFile string) (eTag string, sha string, e error) {
+	hO, e := HeadFile(ctx, pathToFile)
+	if e != nil {
+		return "", "", e
+	}
+	eTag, sha = objectChecksums(hO)
+	if eTag == "" {
+		// Fallback to the ETag stored in the index
+		if n, ok := StatNode(ctx, pathToFile); ok {
+			eTag = n.Etag
+		}
+	}
+	return
+}
+
+// objectChecksums reads the ETag of an object and its SHA-256 hash, if the server exposes one, in its metadata.
+func objectChecksums(hO *s3.HeadObjectOutput) (eTag string, sha string) {
+	if hO.ETag != nil {
+		eTag = *hO.ETag
+	}
+	for k, v := range hO.Metadata {
+		if v != nil && (strings.EqualFold(k, "Sha256") || strings.EqualFold(k, "X-Cells-Sha256")) {
+			sha = *v
+		}
+	}
+	return
+}
+
+// hashedReader feeds the checksums with the bytes that are read for the first time, so that
+// the hashes stay correct when the underlying reader is re-read after a Seek.
+type hashedReader struct {
+	sums   *checksums
+	pos    int64
+	hashed int64
+}
+
+func (h *hashedReader) read(p []byte, n int) {
+	if h.sums == nil {
+		return
+	}
+	if start := h.hashed - h.pos; start >= 0 && start < int64(n) {
+		h.sums.Write(p[start:n])
+		h.hashed = h.pos + int64(n)
+	}
+	h.pos += int64(n)
+}
+
+func (h *hashedReader) seek(pos int64) {
+	if h.sums != nil && pos > h.hashed {
+		// Some bytes will never be hashed
+		h.sums.complete = false
+	}
+	h.pos = pos
+}
+
+// hashLocalSection computes the MD5 of a section of a local file.
+func hashLocalSection(r io.ReaderAt, offset, length int64) ([]byte, error) {
+	h := md5.New()
+	if _, e := io.Copy(h, io.NewSectionReader(r, offset, length)); e != nil {
+		return nil, e
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// multipartETag computes the ETag of an object uploaded by parts of the passed size.
+func multipartETag(content []byte, partSize int) string {
+	h := md5.New()
+	n := 0
+	for start := 0; start < len(content); start += partSize {
+		end := start + partSize
+		if end > len(content) {
+			end = len(content)
+		}
+		p := md5.Sum(content[start:end])
+		h.Write(p[:])
+		n++
+	}
+	return fmt.Sprintf("%x-%d", h.Sum(nil), n)
+}
+
+func TestChecksumsVerify(t *testing.T) {
+	content := []byte("twelve bytes")
+	plain := fmt.Sprintf("%x", md5.Sum(content))
+	sha := fmt.Sprintf("%x", sha256.Sum256(content))
+	other := fmt.Sprintf("%x", md5.Sum([]byte("other")))
+	tests := []struct {
+		name       string
+		partSize   int64
+		guessed    bool
+		read       []byte
+		eTag       string
+		sha        string
+		incomplete bool
+		expected   error
+	}{
+		{"plain md5", 0, false, content, `"` + plain + `"`, "", false, nil},
+		{"plain md5 mismatch", 0, false, content, other, "", false, errChecksumMismatch},
+		{"multipart", 5, false, content, multipartETag(content, 5), "", false, nil},
+		{"multipart exact parts", 4, false, content, multipartETag(content, 4), "", false, nil},
+		{"multipart mismatch", 5, false, content, multipartETag([]byte("twelve bytez"), 5), "", false, errChecksumMismatch},
+		{"other part count", 5, false, content, multipartETag(content, 6), "", false, errCannotVerify},
+		{"guessed part size", 5, true, content, multipartETag(content, 5), "", false, nil},
+		{"guessed part size mismatch", 5, true, content, multipartETag([]byte("twelve bytez"), 5), "", false, errCannotVerify},
+		{"guessed part size with sha", 5, true, content, multipartETag([]byte("twelve bytez"), 5), sha, false, nil},
+		{"single part of a multipart upload", 50, false, content, multipartETag(content, 50), "", false, nil},
+		{"sha mismatch", 0, false, content, plain, other + other, false, errChecksumMismatch},
+		{"unknown etag format", 0, false, content, "not-an-md5", "", false, errCannotVerify},
+		{"unknown etag format with sha", 0, false, content, "not-an-md5", sha, false, nil},
+		{"incomplete", 0, false, content, plain, "", true, errCannotVerify},
+	}
+	for _, tt := range tests {
+		s := newChecksums(tt.partSize)
+		s.partsGuessed = tt.guessed
+		// Write in several chunks that do not match the part boundaries
+		for i := 0; i < len(tt.read); i += 3 {
+			end := i + 3
+			if end > len(tt.read) {
+				end = len(tt.read)
+			}
+			s.Write(tt.read[i:end])
+		}
+		s.complete = !tt.incomplete
+		e := s.verify(tt.eTag, tt.sha)
+		if tt.expected == nil && e != nil || tt.expected != nil && !errors.Is(e, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, e)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -9,8 +11,10 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gosuri/uiprogress"
 
 	"github.com/pydio/cells-sdk-go/models"
@@ -21,6 +25,9 @@ var (
 	QueueSize = 3
 )
 
+// PartFileSuffix is appended to the name of files that are being downloaded.
+const PartFileSuffix = ".part"
+
 // CrawlNode enables processing the scp command step by step.
 type CrawlNode struct {
 	IsLocal bool
@@ -31,25 +38,40 @@ type CrawlNode struct {
 	MTime       time.Time
 	Size        int64
 	NewFileName string
+	// Filter is used by Walk to skip unwanted children, hidden files are skipped if it is nil.
+	Filter *Filter
+	// Config is the configuration of the server of remote nodes, the config of the context is used if it is nil.
+	Config *CecConfig
+	// Ignored lists the local entries that have been skipped while walking this node: special files,
+	// and symbolic links that are not followed.
+	Ignored []*FileReport
+
+	// object caches the S3 metadata of a remote file, see objectInfo.
+	object *s3.HeadObjectOutput
 
 	os.FileInfo
 	models.TreeNode
 }
 
-func NewCrawler(target string, isSrcLocal bool) (*CrawlNode, error) {
+func NewCrawler(ctx context.Context, target string, isSrcLocal bool) (*CrawlNode, error) {
 	if isSrcLocal {
 		target, _ = filepath.Abs(target)
 		i, e := os.Stat(target)
 		if e != nil {
 			return nil, e
 		}
+		if !i.IsDir() && !i.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a regular file", target)
+		}
 		return NewLocalNode(target, i), nil
 	} else {
-		n, b := StatNode(target)
+		n, b := StatNode(ctx, target)
 		if !b {
 			return nil, fmt.Errorf("no node found at %s", target)
 		}
-		return NewRemoteNode(n), nil
+		r := NewRemoteNode(n)
+		r.Config = configFrom(ctx)
+		return r, nil
 	}
 }
 
@@ -81,8 +103,19 @@ func NewRemoteNode(t *models.TreeNode) *CrawlNode {
 }
 
 func NewTarget(target string, source *CrawlNode, rename bool) *CrawlNode {
+	return newTarget(target, source, rename, !source.IsLocal)
+}
+
+// NewRemoteTarget creates the target node for copying a remote source to the server whose config is carried by the context.
+func NewRemoteTarget(ctx context.Context, target string, source *CrawlNode, rename bool) *CrawlNode {
+	c := newTarget(target, source, rename, false)
+	c.Config = configFrom(ctx)
+	return c
+}
+
+func newTarget(target string, source *CrawlNode, rename, isLocal bool) *CrawlNode {
 	c := &CrawlNode{
-		IsLocal:  !source.IsLocal,
+		IsLocal:  isLocal,
 		IsDir:    source.IsDir,
 		FullPath: target,
 		RelPath:  "",
@@ -105,7 +138,18 @@ func NewTarget(target string, source *CrawlNode, rename bool) *CrawlNode {
 }
 
 // Walk prepares the list of single upload/download nodes that we process in a second time.
-func (c *CrawlNode) Walk(current ...string) (children []*CrawlNode, e error) {
+func (c *CrawlNode) Walk(ctx context.Context, current ...string) (children []*CrawlNode, e error) {
+	e = c.WalkFunc(ctx, func(n *CrawlNode) error {
+		children = append(children, n)
+		return nil
+	}, current...)
+	return
+}
+
+// WalkFunc passes each node of the tree to the callback as soon as it is discovered, folders always coming before
+// their children. It stops at the first error returned by the callback, or when the context is cancelled.
+func (c *CrawlNode) WalkFunc(ctx context.Context, callback func(n *CrawlNode) error, current ...string) error {
+	ctx = c.context(ctx)
 	crt := ""
 	if len(current) > 0 {
 		crt = current[0]
@@ -114,56 +158,60 @@ func (c *CrawlNode) Walk(current ...string) (children []*CrawlNode, e error) {
 	// Source is a single file
 	if !c.IsDir {
 		c.RelPath = c.Base()
-		children = append(children, c)
-		return
+		return callback(c)
 	}
 
 	if c.IsLocal {
-		e = filepath.Walk(filepath.Join(c.FullPath, crt), func(p string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if strings.HasPrefix(filepath.Base(p), ".") {
-				return nil
-			}
-			n := NewLocalNode(p, info)
-			n.RelPath = strings.TrimPrefix(n.FullPath, c.FullPath)
-			children = append(children, n)
+		root := filepath.Join(c.FullPath, crt)
+		info, e := os.Stat(root)
+		if e != nil {
+			return e
+		}
+		return c.walkLocal(ctx, root, info, nil, callback)
+	}
+
+	return WalkBulkMetaNode(ctx, path.Join(c.FullPath, crt, "*"), func(n *models.TreeNode) error {
+		remote := NewRemoteNode(n)
+		remote.RelPath = strings.TrimPrefix(remote.FullPath, c.FullPath)
+		remote.Config = c.Config
+		if c.Filter.Excluded(remote.RelPath, remote.IsDir) {
 			return nil
-		})
-	} else {
-		nn, er := GetBulkMetaNode(path.Join(c.FullPath, crt, "*"))
-		if er != nil {
-			e = er
-			return
 		}
-		for _, n := range nn {
-			remote := NewRemoteNode(n)
-			remote.RelPath = strings.TrimPrefix(remote.FullPath, c.FullPath)
-			children = append(children, remote)
-			if n.Type == models.TreeNodeTypeCOLLECTION {
-				cc, er := c.Walk(remote.RelPath)
-				if er != nil {
-					e = er
-					return
-				}
-				children = append(children, cc...)
-			}
+		if e := callback(remote); e != nil {
+			return e
 		}
-	}
-	return
+		if remote.IsDir {
+			return c.WalkFunc(ctx, callback, remote.RelPath)
+		}
+		return nil
+	})
 }
 
 // MkdirAll prepares a recursive scp by first creating all necessary folders under the target root folder.
-func (c *CrawlNode) MkdirAll(dd []*CrawlNode, pool *BarsPool) error {
+func (c *CrawlNode) MkdirAll(ctx context.Context, dd []*CrawlNode, pool *BarsPool) error {
+	if e := c.createRoot(ctx); e != nil {
+		return e
+	}
+	var folders []*CrawlNode
+	for _, d := range dd {
+		if d.IsDir {
+			folders = append(folders, d)
+		}
+	}
+	return c.createFolders(ctx, folders, pool)
+}
 
-	var createRoot bool
-	var mm []*models.TreeNode
+// createRoot makes sure that the target root folder exists.
+func (c *CrawlNode) createRoot(ctx context.Context) error {
+	ctx = c.context(ctx)
 	if !c.IsLocal {
-		// Remote : append root if required
-		if tn, b := StatNode(c.FullPath); !b {
-			mm = append(mm, &models.TreeNode{Path: c.FullPath, Type: models.TreeNodeTypeCOLLECTION})
-			createRoot = true
+		// Remote : create root if required
+		if tn, b := StatNode(ctx, c.FullPath); !b {
+			if DryRun {
+				fmt.Println("MkDir: \t", c.FullPath)
+				return nil
+			}
+			return TreeCreateNodes(ctx, []*models.TreeNode{{Path: c.FullPath, Type: models.TreeNodeTypeCOLLECTION}})
 		} else if tn.Type != models.TreeNodeTypeCOLLECTION {
 			// target root is not a folder, fail fast.
 			return fmt.Errorf("%s exists on the server and is not a folder, cannot upload there", c.FullPath)
@@ -177,11 +225,17 @@ func (c *CrawlNode) MkdirAll(dd []*CrawlNode, pool *BarsPool) error {
 			}
 		}
 	}
+	return nil
+}
+
+// createFolders creates the passed source folders under the target root, that must already exist.
+func (c *CrawlNode) createFolders(ctx context.Context, dd []*CrawlNode, pool *BarsPool) error {
+	ctx = c.context(ctx)
+	var mm []*models.TreeNode
 	for _, d := range dd {
-		if !d.IsDir {
-			continue
-		}
-		if d.RelPath == "" && createRoot {
+		if d.RelPath == "" {
+			// Root has already been created
+			pool.Done()
 			continue
 		}
 		newFolder := c.Join(c.FullPath, d.RelPath)
@@ -200,7 +254,7 @@ func (c *CrawlNode) MkdirAll(dd []*CrawlNode, pool *BarsPool) error {
 		}
 	}
 	if !c.IsLocal && !DryRun && len(mm) > 0 {
-		e := TreeCreateNodes(mm)
+		e := TreeCreateNodes(ctx, mm)
 		if e != nil {
 			return e
 		}
@@ -213,14 +267,118 @@ func (c *CrawlNode) MkdirAll(dd []*CrawlNode, pool *BarsPool) error {
 }
 
 // CopyAll parallely performs the real upload/download of files that have been prepared during the Walk step.
-func (c *CrawlNode) CopyAll(dd []*CrawlNode, pool *BarsPool) (errs []error) {
+// The entries that have been ignored while walking the source are recorded in the summary.
+func (c *CrawlNode) CopyAll(ctx context.Context, source *CrawlNode, dd []*CrawlNode, pool *BarsPool) (errs []error) {
+	nodes := make(chan *CrawlNode)
+	go func() {
+		defer close(nodes)
+		for _, d := range dd {
+			if !d.IsDir {
+				pool.discovered(d)
+				select {
+				case nodes <- d:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	errs = c.transferAll(ctx, nodes, pool)
+	pool.Summary.ignored(source.Ignored...)
+	return errs
+}
+
+// Transfer walks the source tree and processes its nodes as soon as they are discovered, instead of waiting
+// for the end of the walk: folders are created just in time and files are copied by QueueSize parallel workers.
+// The total of the global progress bar grows as new nodes are discovered.
+func (c *CrawlNode) Transfer(ctx context.Context, source *CrawlNode, pool *BarsPool) []error {
+	if e := c.createRoot(ctx); e != nil {
+		pool.Stop()
+		return []error{e}
+	}
+
+	nodes := make(chan *CrawlNode, 10*QueueSize)
+	var walkErr error
+	pool.startDiscovery()
+	go func() {
+		defer close(nodes)
+		walkErr = source.WalkFunc(ctx, func(n *CrawlNode) error {
+			// Count the node before a worker can process it, so that the total is never behind the done nodes
+			pool.discovered(n)
+			select {
+			case nodes <- n:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		pool.stopDiscovery()
+	}()
+
+	errs := c.transferAll(ctx, nodes, pool)
+	// The walk is over once all nodes have been consumed
+	pool.Summary.ignored(source.Ignored...)
+	if walkErr != nil && walkErr != ctx.Err() {
+		errs = append(errs, walkErr)
+	}
+	return errs
+}
+
+// transferAll consumes the nodes channel until it is closed. Folders are batched and created before
+// the next file is processed, so that files are always copied in an existing parent folder.
+func (c *CrawlNode) transferAll(ctx context.Context, nodes <-chan *CrawlNode, pool *BarsPool) (errs []error) {
 	idx := -1
 	buf := make(chan struct{}, QueueSize)
 	wg := &sync.WaitGroup{}
-	for _, d := range dd {
+	errMux := &sync.Mutex{}
+	appendErr := func(e error) {
+		errMux.Lock()
+		errs = append(errs, e)
+		errMux.Unlock()
+	}
+
+	var folders []*CrawlNode
+	flushFolders := func() {
+		if len(folders) == 0 {
+			return
+		}
+		if ctx.Err() != nil {
+			folders = nil
+			return
+		}
+		if e := c.createFolders(ctx, folders, pool); e != nil {
+			appendErr(e)
+		}
+		folders = nil
+	}
+
+	for {
+		var d *CrawlNode
+		var ok bool
+		select {
+		case d, ok = <-nodes:
+		default:
+			// Nothing new for now: take this chance to create pending folders
+			flushFolders()
+			d, ok = <-nodes
+		}
+		if !ok {
+			break
+		}
 		if d.IsDir {
+			folders = append(folders, d)
+			if len(folders) >= int(BulkPageSize) {
+				flushFolders()
+			}
+			continue
+		}
+		flushFolders()
+		if ctx.Err() != nil {
+			// Cancelled: only drain the remaining nodes
+			pool.Summary.notStarted(newFileReport(d, c.targetPath(d)))
 			continue
 		}
+
 		buf <- struct{}{}
 		idx++
 		barSize := d.Size
@@ -234,36 +392,88 @@ func (c *CrawlNode) CopyAll(dd []*CrawlNode, pool *BarsPool) (errs []error) {
 		go func(src *CrawlNode, barId int) {
 			defer func() {
 				wg.Done()
+				pool.fileDone(bar)
 				pool.Done()
 				<-buf
 			}()
-			if !c.IsLocal {
-				if e := c.upload(src, bar); e != nil {
-					errs = append(errs, e)
-				}
-				if emptyFile {
-					bar.Set(1)
-				}
-			} else {
-				if e := c.download(src, bar); e != nil {
-					errs = append(errs, e)
-				}
-				if emptyFile {
-					bar.Set(1)
+			targetCtx := c.context(ctx)
+			report := newFileReport(src, c.targetPath(src))
+			dest, e := c.resolveConflict(targetCtx, src, report.Target)
+			if e == nil && dest == "" {
+				appendToBar(bar, "(skipped, already exists)")
+				bar.Set(bar.Total)
+				pool.Summary.skipped(report)
+				return
+			}
+			if e == nil {
+				report.Target = dest
+				if !c.IsLocal {
+					e = c.upload(targetCtx, src, dest, bar)
+					if e == nil {
+						e = UserMeta.apply(targetCtx, src.RelPath, dest)
+					}
+				} else {
+					e = c.download(src.context(ctx), src, dest, bar)
 				}
 			}
+			if e != nil {
+				appendErr(e)
+				pool.Summary.failed(report, e, ctx.Err() != nil)
+				return
+			}
+			if emptyFile {
+				bar.Set(1)
+			}
+			pool.Summary.transferred(report)
 		}(d, idx)
 	}
+	flushFolders()
 	wg.Wait()
 	pool.Stop()
 	return
 }
 
-func (c *CrawlNode) upload(src *CrawlNode, bar *uiprogress.Bar) error {
+// upload sends a local file to the server, or streams a file from another server when the source is remote.
+// When VerifyTransfers is set, the checksums computed while sending the file are compared with the ones
+// of the uploaded object, and the upload is started again in case of mismatch.
+func (c *CrawlNode) upload(ctx context.Context, src *CrawlNode, fp string, bar *uiprogress.Bar) error {
+	for attempt := 0; ; attempt++ {
+		var sums *checksums
+		var e error
+		if src.IsLocal {
+			sums, e = c.sendFile(ctx, src, fp, bar)
+		} else {
+			sums, e = c.streamFile(ctx, src, fp, bar)
+		}
+		if e != nil || !VerifyTransfers {
+			return e
+		}
+		// Only a content that differs is sent again, not one whose checksums could not be retrieved
+		eTag, sha, e := remoteChecksums(ctx, fp)
+		if e != nil {
+			return fmt.Errorf("%s: cannot retrieve remote checksums: %w", fp, e)
+		}
+		e = sums.verify(eTag, sha)
+		if errors.Is(e, errCannotVerify) {
+			appendToBar(bar, "(unverified)")
+			return nil
+		} else if e == nil || attempt >= VerifyRetries {
+			if e != nil {
+				e = fmt.Errorf("%s: %w", fp, e)
+			}
+			return e
+		}
+		appendToBar(bar, "checksum mismatch, retrying...")
+	}
+}
+
+// sendFile performs the effective upload and returns the checksums of the sent content.
+func (c *CrawlNode) sendFile(ctx context.Context, src *CrawlNode, fp string, bar *uiprogress.Bar) (*checksums, error) {
 	file, e := os.Open(src.FullPath)
 	if e != nil {
-		return e
+		return nil, e
 	}
+	defer file.Close()
 	stats, _ := file.Stat()
 	wrapper := &PgReader{
 		Reader: file,
@@ -274,57 +484,340 @@ func (c *CrawlNode) upload(src *CrawlNode, bar *uiprogress.Bar) error {
 	}
 	errChan, done := wrapper.CreateErrorChan()
 	defer close(done)
-	bname := src.RelPath
-	if c.NewFileName != "" {
-		bname = c.NewFileName
-	}
 	var computeMD5 bool
 
-	fp := c.Join(c.FullPath, bname)
 	// Handle corner case when trying to upload a file and *folder* with same name already exists at target path
-	if tn, b := StatNode(fp); b && tn.Type == models.TreeNodeTypeCOLLECTION {
+	if tn, b := StatNode(ctx, fp); b && tn.Type == models.TreeNodeTypeCOLLECTION {
 		// target root is not a folder, fail fast.
-		return fmt.Errorf("cannot upload file to %s, a folder with same name already exists at target path", fp)
+		return nil, fmt.Errorf("cannot upload file to %s, a folder with same name already exists at target path", fp)
 	}
 	wrapper.double = false
-	if stats.Size() < (100 * 1024 * 1024) {
-		if _, err := PutFile(fp, wrapper, false, errChan); err != nil {
-			return err
+	if Encrypt {
+		// Encrypted content is streamed, it cannot be resumed nor sent by parts in parallel
+		return c.sendStream(ctx, file, stats.Size(), timeMetadata(src.MTime), fp, bar)
+	}
+	head, e := wrapper.Peek()
+	if e != nil {
+		return nil, e
+	}
+	contentType := detectContentType(fp, head)
+	var sums *checksums
+	if stats.Size() < MultipartThreshold {
+		if VerifyTransfers {
+			sums = newChecksums(0)
+			wrapper.hashes.sums = sums
+		}
+		if _, err := PutFile(ctx, fp, wrapper, timeMetadata(src.MTime), contentType, false, errChan); err != nil {
+			return nil, err
 		}
 	} else {
 		// if the file is equal or bigger than 5GB we will compute the md5 and pass it as a custom metadata
 		if stats.Size() >= (5 * 1024 * 1024 * 1024) {
 			computeMD5 = true
 		}
-		if err := uploadManager(fp, wrapper, computeMD5, errChan); err != nil {
-			return err
+		var uploaded int64
+		progress := func(n int64) {
+			bar.Set(int(atomic.AddInt64(&uploaded, n)))
+		}
+		parts, err := uploadManager(ctx, fp, file, timeMetadata(src.MTime), contentType, computeMD5, progress, errChan)
+		if err != nil {
+			return nil, err
+		}
+		if VerifyTransfers {
+			// Parts have been sent in parallel: full hashes are computed afterwards, only if required.
+			sums = newChecksums(0)
+			sums.parts = parts
+			if _, err := io.Copy(sums, io.NewSectionReader(file, 0, stats.Size())); err != nil {
+				return nil, err
+			}
 		}
 	}
-	return nil
+	return sums, nil
 }
 
-func (c *CrawlNode) download(src *CrawlNode, bar *uiprogress.Bar) error {
-	reader, length, e := GetFile(src.FullPath)
+// streamFile copies a remote file to the target server without writing it on the local disk: the content
+// is read from the source server and sent by parts to the target server as it is received.
+func (c *CrawlNode) streamFile(ctx context.Context, src *CrawlNode, fp string, bar *uiprogress.Bar) (*checksums, error) {
+	h, reader, e := getObject(src.context(ctx), src.FullPath, 0)
 	if e != nil {
-		return e
+		return nil, e
 	}
+	defer reader.Close()
+	// Encrypted content is copied as is, with the metadata required to decrypt it
+	meta := encryptionMetadata(h.Metadata)
+	if PreserveTimes {
+		if meta == nil {
+			meta = make(map[string]*string)
+		}
+		for k, v := range timeMetadata(preservedMTime(h.Metadata, src.MTime)) {
+			meta[k] = v
+		}
+	}
+	return c.sendStream(ctx, reader, src.Size, meta, fp, bar)
+}
+
+// sendStream uploads content that cannot be rewound, and whose size is known, by parts as it is read.
+// The checksums of the content are computed on the fly when VerifyTransfers is set.
+// If Encrypt is set, the content is encrypted first, unless the metadata show that it already is.
+func (c *CrawlNode) sendStream(ctx context.Context, reader io.Reader, size int64, meta map[string]*string, fp string, bar *uiprogress.Bar) (*checksums, error) {
+	if Encrypt && !isEncrypted(meta) {
+		var e error
+		if reader, meta, e = encryptionKey().encrypt(reader, meta, size); e != nil {
+			return nil, e
+		}
+		size = encryptedSize(size)
+		bar.Total = int(size)
+	}
+	partSize := partSizeFor(size)
 	wrapper := &PgReader{
 		Reader: reader,
 		bar:    bar,
-		total:  length,
+		total:  int(size),
+	}
+	contentType := encryptedContentType
+	if !isEncrypted(meta) {
+		head, e := wrapper.Peek()
+		if e != nil {
+			return nil, e
+		}
+		contentType = detectContentType(fp, head)
 	}
-	bname := src.RelPath
-	if c.NewFileName != "" {
-		bname = c.NewFileName
+	var sums *checksums
+	if VerifyTransfers {
+		sums = newChecksums(partSize)
+		wrapper.hashes.sums = sums
 	}
-	downloadToLocation := c.Join(c.FullPath, bname)
-	writer, e := os.OpenFile(downloadToLocation, os.O_CREATE|os.O_WRONLY, 0755)
+	if e := putStream(ctx, fp, wrapper, partSize, meta, contentType); e != nil {
+		return nil, e
+	}
+	return sums, nil
+}
+
+// download retrieves the remote file in a sidecar ".part" file that is only renamed to its final name
+// once its size matches the size of the remote node. If a ".part" file is found, download resumes
+// from its current length, unless the remote file has been modified since it was last written.
+// Files that are bigger than PartSize are retrieved by parts, with PartsConcurrency parallel ranged requests.
+// When VerifyTransfers is set, the checksums of the downloaded file are compared with the remote ETag
+// before the renaming, and the download is started again from scratch in case of mismatch.
+// When PreserveTimes is set, the modification time of the remote file is applied to the local file.
+func (c *CrawlNode) download(ctx context.Context, src *CrawlNode, downloadToLocation string, bar *uiprogress.Bar) error {
+	for attempt := 0; ; attempt++ {
+		e := c.receiveFile(ctx, src, downloadToLocation, bar)
+		if errors.Is(e, errCannotVerify) {
+			appendToBar(bar, "(unverified)")
+			return nil
+		} else if e == nil || !VerifyTransfers || attempt >= VerifyRetries || !errors.Is(e, errChecksumMismatch) {
+			return e
+		}
+		appendToBar(bar, "checksum mismatch, retrying...")
+	}
+}
+
+func (c *CrawlNode) receiveFile(ctx context.Context, src *CrawlNode, downloadToLocation string, bar *uiprogress.Bar) error {
+	partLocation := downloadToLocation + PartFileSuffix
+	// Metadata are retrieved once and shared by the decryption, the verification and the modification time
+	h, e := src.objectInfo(ctx)
+	if e != nil {
+		return e
+	}
+	if isEncrypted(h.Metadata) {
+		return c.receiveEncrypted(ctx, src, h, partLocation, downloadToLocation, bar)
+	}
+
+	parallel := PartsConcurrency > 1 && src.Size > PartSize
+	var offset int64
+	if j := loadDownloadJournal(configFrom(ctx).Url, partLocation); j != nil && !parallel {
+		// File has been written by parts, in any order: it cannot be resumed sequentially
+		j.remove()
+	} else if i, e := os.Stat(partLocation); e == nil && i.Size() <= src.Size && !src.MTime.After(i.ModTime()) {
+		offset = i.Size()
+	}
+	writer, e := os.OpenFile(partLocation, os.O_CREATE|os.O_RDWR, 0755)
 	if e != nil {
 		return e
 	}
 	defer writer.Close()
-	_, e = io.Copy(writer, wrapper)
-	return e
+
+	var sums *checksums
+	if parallel {
+		var received int64
+		progress := func(n int64) {
+			bar.Set(int(atomic.AddInt64(&received, n)))
+		}
+		if e = downloadParts(ctx, src, writer, offset, progress); e != nil {
+			return e
+		}
+		if VerifyTransfers {
+			// Parts have been received in parallel: hash the whole file once it is complete
+			sums = newDownloadChecksums(src.Size)
+			if _, e = io.Copy(sums, io.NewSectionReader(writer, 0, src.Size)); e != nil {
+				return e
+			}
+		}
+	} else if sums, e = c.receiveStream(ctx, src, writer, offset, bar); e != nil {
+		return e
+	}
+	if e = writer.Close(); e != nil {
+		return e
+	}
+
+	if i, e := os.Stat(partLocation); e != nil {
+		return e
+	} else if i.Size() != src.Size {
+		return fmt.Errorf("downloaded %d bytes for %s, expected %d: partial file is kept at %s to resume later", i.Size(), src.FullPath, src.Size, partLocation)
+	}
+
+	var verifyErr error
+	if sums != nil {
+		eTag, sha := objectChecksums(h)
+		if eTag == "" {
+			eTag = src.Etag
+		}
+		if verifyErr = sums.verify(eTag, sha); verifyErr != nil && !errors.Is(verifyErr, errCannotVerify) {
+			_ = os.Remove(partLocation)
+			return fmt.Errorf("%s: %w", src.FullPath, verifyErr)
+		}
+	}
+	if e := completeDownload(ctx, src, partLocation, downloadToLocation); e != nil {
+		return e
+	}
+	return verifyErr
+}
+
+// completeDownload moves a downloaded file to its final location.
+func completeDownload(ctx context.Context, src *CrawlNode, partLocation, downloadToLocation string) error {
+	if e := os.Rename(partLocation, downloadToLocation); e != nil {
+		return e
+	}
+	if PreserveTimes {
+		mTime := src.originalMTime(ctx)
+		if e := os.Chtimes(downloadToLocation, mTime, mTime); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// receiveEncrypted downloads a file that has been encrypted on upload and decrypts it on the fly. As chunks
+// are authenticated in sequence, it is always downloaded from the start with a single request.
+// Checksums are not compared, AES-GCM already guarantees that the content has not been altered.
+func (c *CrawlNode) receiveEncrypted(ctx context.Context, src *CrawlNode, h *s3.HeadObjectOutput, partLocation, downloadToLocation string, bar *uiprogress.Bar) error {
+	writer, e := os.OpenFile(partLocation, os.O_CREATE|os.O_RDWR, 0755)
+	if e != nil {
+		return e
+	}
+	defer writer.Close()
+	var size int64
+	e = Retry.Do(ctx, func() error {
+		if e := writer.Truncate(0); e != nil {
+			return permanent(e)
+		}
+		if _, e := writer.Seek(0, io.SeekStart); e != nil {
+			return permanent(e)
+		}
+		body, e := readObject(ctx, src.FullPath, 0)
+		if e != nil {
+			return permanent(e)
+		}
+		defer body.Close()
+		reader, plainSize, e := decryptObject(src.FullPath, body, h.Metadata, *h.ContentLength)
+		if e != nil {
+			return permanent(e)
+		}
+		size = plainSize
+		bar.Total = int(size)
+		_, e = io.Copy(writer, &PgReader{Reader: reader, bar: bar, total: int(size)})
+		return e
+	})
+	if e != nil {
+		_ = os.Remove(partLocation)
+		return e
+	}
+	if e = writer.Close(); e != nil {
+		return e
+	}
+	if i, e := os.Stat(partLocation); e != nil {
+		return e
+	} else if i.Size() != size {
+		_ = os.Remove(partLocation)
+		return fmt.Errorf("decrypted %d bytes for %s, expected %d", i.Size(), src.FullPath, size)
+	}
+	return completeDownload(ctx, src, partLocation, downloadToLocation)
+}
+
+// receiveStream downloads the remote file with a single request, appending its content to the local file
+// from the passed offset.
+func (c *CrawlNode) receiveStream(ctx context.Context, src *CrawlNode, writer *os.File, offset int64, bar *uiprogress.Bar) (*checksums, error) {
+	// Drop stale content if we do not resume
+	if e := writer.Truncate(offset); e != nil {
+		return nil, e
+	}
+
+	var sums *checksums
+	var target io.Writer = writer
+	if VerifyTransfers {
+		sums = newDownloadChecksums(src.Size)
+		// Hash the content that has been downloaded by a previous run
+		if _, e := io.Copy(sums, io.NewSectionReader(writer, 0, offset)); e != nil {
+			return nil, e
+		}
+		target = io.MultiWriter(writer, sums)
+	}
+	if _, e := writer.Seek(offset, io.SeekStart); e != nil {
+		return nil, e
+	}
+
+	if offset < src.Size {
+		wrapper := &PgReader{
+			bar:   bar,
+			total: int(src.Size),
+			read:  int(offset),
+		}
+		bar.Set(wrapper.read)
+		// If the connection breaks while the content is read, request the remaining bytes only
+		e := Retry.Do(ctx, func() error {
+			reader, e := readObject(ctx, src.FullPath, int64(wrapper.read))
+			if e != nil {
+				return permanent(e)
+			}
+			defer reader.Close()
+			wrapper.Reader = reader
+			_, e = io.Copy(target, wrapper)
+			return e
+		})
+		if e != nil {
+			return nil, e
+		}
+	}
+	return sums, nil
+}
+
+// context returns a context that carries the config of the node, if any.
+func (c *CrawlNode) context(ctx context.Context) context.Context {
+	if c.Config != nil {
+		return WithConfig(ctx, c.Config)
+	}
+	return ctx
+}
+
+// objectInfo retrieves the S3 metadata of a remote file once, so that they are shared by the comparison of
+// the file, its download, the verification of its checksums and the preservation of its modification time.
+func (c *CrawlNode) objectInfo(ctx context.Context) (*s3.HeadObjectOutput, error) {
+	if c.object == nil {
+		h, e := HeadFile(c.context(ctx), c.FullPath)
+		if e != nil {
+			return nil, e
+		}
+		c.object = h
+	}
+	return c.object, nil
+}
+
+// appendToBar displays an additional message on the right of the progress bar.
+func appendToBar(bar *uiprogress.Bar, msg string) {
+	bar.AppendFunc(func(b *uiprogress.Bar) string {
+		return msg
+	})
 }
 
 func (c *CrawlNode) Join(p ...string) string {
@@ -362,23 +855,45 @@ func (c *CrawlNode) Dir() string {
 
 type BarsPool struct {
 	*uiprogress.Progress
-	showGlobal bool
-	nodesBar   *uiprogress.Bar
+	showGlobal  bool
+	nodesBar    *uiprogress.Bar
+	discovering bool
+	discoveries int
+	mux         sync.Mutex
+	// Summary records the outcome of each processed file.
+	Summary *TransferSummary
+
+	// Aggregate progress, used to compute the rate and ETA. Only the bars of the files that are being
+	// transferred are kept, the bytes of the completed files are added to doneBytes.
+	started    time.Time
+	fileBars   map[*uiprogress.Bar]struct{}
+	doneBytes  int64
+	filesTotal int
+	filesDone  int
+	totalBytes int64
+
+	logMode bool
+	stopLog chan struct{}
 }
 
 func NewBarsPool(showGlobal bool, totalNodes int, refreshInterval time.Duration) *BarsPool {
-	b := &BarsPool{}
+	b := &BarsPool{Summary: &TransferSummary{}, fileBars: make(map[*uiprogress.Bar]struct{})}
 	b.Progress = uiprogress.New()
 	b.Progress.SetRefreshInterval(refreshInterval)
 	b.showGlobal = showGlobal
 	if showGlobal {
 		b.nodesBar = b.AddBar(totalNodes)
 		b.nodesBar.PrependCompleted()
-		b.nodesBar.AppendFunc(func(b *uiprogress.Bar) string {
-			if b.Current() == b.Total {
-				return fmt.Sprintf("Transferred %d/%d files and folders (%s)", b.Current(), b.Total, b.TimeElapsedString())
+		b.nodesBar.AppendFunc(func(bar *uiprogress.Bar) string {
+			b.mux.Lock()
+			discovering := b.discovering
+			b.mux.Unlock()
+			if discovering {
+				return fmt.Sprintf("Transfering %d/%d files or folders (still discovering, %s)", bar.Current(), bar.Total, b.globalStats())
+			} else if bar.Current() == bar.Total {
+				return fmt.Sprintf("Transferred %d/%d files and folders (%s)", bar.Current(), bar.Total, bar.TimeElapsedString())
 			} else {
-				return fmt.Sprintf("Transfering %d/%d files or folders", b.Current()+1, b.Total)
+				return fmt.Sprintf("Transfering %d/%d files or folders (%s)", bar.Current()+1, bar.Total, b.globalStats())
 			}
 		})
 	}
@@ -390,12 +905,48 @@ func (b *BarsPool) Done() {
 		return
 	}
 	b.nodesBar.Incr()
-	if b.nodesBar.Current() == b.nodesBar.Total {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if !b.discovering && b.nodesBar.Current() == b.nodesBar.Total {
 		// Finished, remove all bars
 		b.Bars = []*uiprogress.Bar{b.nodesBar}
 	}
 }
 
+// startDiscovery is called when the nodes are transferred while the source is still being walked.
+func (b *BarsPool) startDiscovery() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.discovering = true
+}
+
+// discovered increments the total number of nodes and bytes to process. The total passed to NewBarsPool
+// is kept until more nodes have been discovered, as the bar cannot be rendered with a zero total.
+func (b *BarsPool) discovered(n *CrawlNode) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if !n.IsDir {
+		b.filesTotal++
+		b.totalBytes += n.Size
+	}
+	if !b.showGlobal {
+		return
+	}
+	b.discoveries++
+	if b.discoveries > b.nodesBar.Total {
+		b.nodesBar.Total = b.discoveries
+	}
+}
+
+func (b *BarsPool) stopDiscovery() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.discovering = false
+	if b.showGlobal && b.nodesBar.Current() == b.nodesBar.Total {
+		b.Bars = []*uiprogress.Bar{b.nodesBar}
+	}
+}
+
 func (b *BarsPool) Get(i int, total int, name string) *uiprogress.Bar {
 	idx := i % QueueSize
 	var nBars []*uiprogress.Bar
@@ -412,19 +963,30 @@ func (b *BarsPool) Get(i int, total int, name string) *uiprogress.Bar {
 	}
 	b.Bars = nBars
 	bar := b.AddBar(total)
+	started := time.Now()
 	bar.PrependCompleted()
 	bar.AppendFunc(func(b *uiprogress.Bar) string {
-		return fmt.Sprintf("%s", name)
+		if b.Current() == b.Total {
+			return name
+		}
+		return fmt.Sprintf("%s (%s)", name, barStats(b, started))
 	})
+	b.mux.Lock()
+	b.fileBars[bar] = struct{}{}
+	b.mux.Unlock()
+	if b.logMode {
+		b.logFileStart(name, total)
+	}
 	return bar
 }
 
 type PgReader struct {
 	io.Reader
 	io.Seeker
-	bar   *uiprogress.Bar
-	total int
-	read  int
+	bar    *uiprogress.Bar
+	total  int
+	read   int
+	hashes hashedReader
 
 	double bool
 	first  bool
@@ -457,7 +1019,9 @@ func (r *PgReader) sendErr(err error) {
 
 func (r *PgReader) Read(p []byte) (n int, err error) {
 	n, err = r.Reader.Read(p)
-	if err == nil {
+	r.hashes.read(p, n)
+	if err == nil || (err != io.EOF && n > 0) {
+		// Bytes received before an error are also counted, so that a broken download resumes after them
 		if r.double {
 			r.read += n / 2
 		} else {
@@ -482,5 +1046,9 @@ func (r *PgReader) Seek(offset int64, whence int) (int64, error) {
 		r.read = int(offset)
 	}
 	r.bar.Set(r.read)
-	return r.Seeker.Seek(offset, whence)
+	pos, err := r.Seeker.Seek(offset, whence)
+	if err == nil {
+		r.hashes.seek(pos)
+	}
+	return pos, err
 }